@@ -6,68 +6,142 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	_ "github.com/likeawizard/polyglot-composer/pkg/logger"
 	"github.com/likeawizard/polyglot-composer/pkg/pgn"
 	"github.com/likeawizard/polyglot-composer/pkg/polyglot"
+	"github.com/likeawizard/tofiks/pkg/board"
 )
 
 func main() {
 	// defer profile.Start(profile.CPUProfile).Stop()
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+
 	ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt)
 
 	var pgnPath, outPath string
 	flag.StringVar(&pgnPath, "pgn", "", "PGN path")
 	flag.StringVar(&outPath, "o", "poly_out.bin", "Polyglot book output name.")
 	flag.IntVar(&polyglot.MoveLimit, "d", 40, "Move depth limit.")
+	flag.BoolVar(&polyglot.Variations, "variations", false, "Add variations (RAVs) to the book, not only the main line.")
+	flag.Uint64Var(&polyglot.VariationWeight, "vw", 100, "Weight of variation moves in percent of main line moves.")
+	flag.BoolVar(&polyglot.Repertoire, "repertoire", false, "Weigh moves by move marks and NAGs (!, ?, $1, ...) instead of the game result.")
+	flag.Func("color", "Only weigh moves of one color in repertoire mode: white or black.", func(name string) error {
+		var err error
+		polyglot.RepertoireColor, err = polyglot.NormalizeColor(name)
+		return err
+	})
+	flag.StringVar(&pgn.Variant, "variant", pgn.VARIANT_STANDARD, "Comma separated variants of games to include: standard, chess960.")
+	flag.StringVar(&pgn.CacheDir, "cache", "", "Directory to cache downloaded PGNs in and reuse on later runs.")
+	flag.BoolVar(&pgn.SkipSetUp, "skip-setup", false, "Skip games starting from a custom position (SetUp/FEN tags).")
+	flag.Func("charset", "Charset of the PGN input: auto, utf-8, latin-1 or windows-1252. Auto decodes lines that are not valid UTF-8 as windows-1252.", func(name string) error {
+		var err error
+		pgn.Charset, err = pgn.NormalizeCharset(name)
+		return err
+	})
+	var parallel int
+	flag.IntVar(&parallel, "j", 1, "Number of sources processed in parallel.")
+	flag.IntVar(&pgn.SplitChunks, "split", 1, "Number of chunks plain PGN files are split into to be parsed in parallel with -j.")
+	flag.IntVar(&pgn.DecodeWorkers, "dj", 1, "Number of goroutines decompressing multi-frame zst and multi-stream bz2 sources.")
+	pgn.LichessFlags(flag.CommandLine)
+	filterFlags := pgn.NewFilterFlags(flag.CommandLine)
+	var players []string
+	flag.Func("player", "Only add moves played by the player. Repeat for aliases. Saves separate <o>_white and <o>_black books.", func(name string) error {
+		players = append(players, name)
+		return nil
+	})
 	flag.Parse()
 
 	if pgnPath == "" {
 		fmt.Println("no pgn provided")
 		return
 	}
-	pb := polyglot.NewPolyglotBook()
+	// Annotated repertoire files and player games rarely carry results or ratings the elite filter expects
+	autoPreset := pgn.FILTER_ELITE
+	if polyglot.Repertoire || players != nil {
+		autoPreset = pgn.FILTER_NONE
+	}
+	filter, err := filterFlags.Filter(autoPreset)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	books := map[string]*polyglot.Book{outPath: polyglot.NewPolyglotBook()}
+	if players != nil {
+		name := strings.TrimSuffix(outPath, filepath.Ext(outPath))
+		books = map[string]*polyglot.Book{
+			name + "_white" + filepath.Ext(outPath): polyglot.NewPlayerBook(board.WHITE, players...),
+			name + "_black" + filepath.Ext(outPath): polyglot.NewPlayerBook(board.BLACK, players...),
+		}
+	}
 	sources, err := pgn.ParsePath(pgnPath)
 	if err != nil {
 		fmt.Printf("could not parse pgn path: %s", err)
 	}
 
+	progress := pgn.NewProgressGroup(len(sources))
+	jobs := make(chan struct{}, max(parallel, 1))
+	var sourceWG sync.WaitGroup
+
 SourceLoop:
 	for _, path := range sources {
 		select {
 		case <-ctx.Done():
 			break SourceLoop
-		default:
+		case jobs <- struct{}{}:
 		}
-		pp, err := pgn.NewPGNParser(path, true)
+		pp, err := pgn.NewPGNParser(path, filter)
 		if err != nil {
 			fmt.Printf("could not load pgn file: %s with error: %s\n", path, err)
+			<-jobs
 			continue
 		}
+		pp.SetProgressGroup(progress)
 
-		pgnChan := make(chan *pgn.PGN, 20)
-		go func() {
-			for pp.Scan(ctx) {
-				pgnChan <- pp.PGN()
-			}
-			pp.Close()
-			pp.Progress(true)
-			close(pgnChan)
-		}()
+		sourceWG.Add(1)
+		go func(pp *pgn.Parser) {
+			defer func() {
+				<-jobs
+				sourceWG.Done()
+			}()
 
-		var wg sync.WaitGroup
-		for game := range pgnChan {
-			wg.Add(1)
-			go func(game *pgn.PGN) {
-				defer wg.Done()
-				pb.AddFromPGN(game)
-			}(game)
-		}
-		fmt.Println()
-		wg.Wait()
+			pgnChan := make(chan *pgn.PGN, 20)
+			go func() {
+				for pp.Scan(ctx) {
+					pgnChan <- pp.PGN()
+				}
+				if err := pp.Err(); err != nil {
+					fmt.Printf("\ncould not read pgn with error: %s\n", err)
+				}
+				pp.Close()
+				pp.Progress(true)
+				close(pgnChan)
+			}()
+
+			var wg sync.WaitGroup
+			for game := range pgnChan {
+				wg.Add(1)
+				go func(game *pgn.PGN) {
+					defer wg.Done()
+					for _, pb := range books {
+						pb.AddFromPGN(game)
+					}
+				}(game)
+			}
+			wg.Wait()
+		}(pp)
 	}
+	sourceWG.Wait()
+	fmt.Println()
 
-	pb.SaveBook(outPath)
-	fmt.Printf("Book saved: %v\n", outPath)
+	for path, pb := range books {
+		pb.SaveBook(path)
+		fmt.Printf("Book saved: %v\n", path)
+	}
 }
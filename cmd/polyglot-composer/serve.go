@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/likeawizard/polyglot-composer/pkg/explorer"
+	"github.com/likeawizard/polyglot-composer/pkg/polyglot"
+)
+
+// Serve a polyglot book as a local opening explorer. Usage: polyglot-composer serve -book <book.bin> [-addr <host:port>].
+func serve(args []string) {
+	var bookPath, addr string
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.StringVar(&bookPath, "book", "poly_out.bin", "Polyglot book to serve.")
+	flags.StringVar(&addr, "addr", "localhost:8080", "Address to listen on.")
+	_ = flags.Parse(args)
+
+	// LoadBook only reports a missing book and would serve an empty one
+	if _, err := os.Stat(bookPath); err != nil {
+		fmt.Printf("could not open book: %s\n", err)
+		os.Exit(1)
+	}
+	pb := polyglot.LoadBook(bookPath)
+	fmt.Printf("Serving book: %v on http://%s/book\n", bookPath, addr)
+	if err := http.ListenAndServe(addr, explorer.NewServer(pb)); err != nil {
+		fmt.Printf("server stopped with error: %s\n", err)
+	}
+}
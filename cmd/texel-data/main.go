@@ -19,74 +19,110 @@ func main() {
 	var pgnPath, outPath string
 	flag.StringVar(&pgnPath, "pgn", "", "PGN path")
 	flag.StringVar(&outPath, "o", "texel_data.txt", "Texel data output")
+	var parallel int
+	flag.IntVar(&parallel, "j", 1, "Number of sources processed in parallel.")
+	flag.IntVar(&pgn.SplitChunks, "split", 1, "Number of chunks plain PGN files are split into to be parsed in parallel with -j.")
+	flag.IntVar(&pgn.DecodeWorkers, "dj", 1, "Number of goroutines decompressing multi-frame zst and multi-stream bz2 sources.")
+	flag.StringVar(&pgn.CacheDir, "cache", "", "Directory to cache downloaded PGNs in and reuse on later runs.")
+	flag.BoolVar(&pgn.SkipSetUp, "skip-setup", false, "Skip games starting from a custom position (SetUp/FEN tags).")
+	flag.Func("charset", "Charset of the PGN input: auto, utf-8, latin-1 or windows-1252. Auto decodes lines that are not valid UTF-8 as windows-1252.", func(name string) error {
+		var err error
+		pgn.Charset, err = pgn.NormalizeCharset(name)
+		return err
+	})
+	pgn.LichessFlags(flag.CommandLine)
+	filterFlags := pgn.NewFilterFlags(flag.CommandLine)
 	flag.Parse()
 
 	if pgnPath == "" {
 		fmt.Println("no pgn provided")
 		return
 	}
+	filter, err := filterFlags.Filter(pgn.FILTER_NONE)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 	sources, err := pgn.ParsePath(pgnPath)
 	if err != nil {
 		fmt.Printf("could not parse pgn path: %s", err)
 	}
 
+	// Single writer shared by all sources
+	fenChan := make(chan string)
+	var writeWG sync.WaitGroup
+	writeWG.Add(1)
+	go func() {
+		file, err := os.Create(outPath)
+		if err != nil {
+			fmt.Println("failed opening file for writing: ", outPath)
+		}
+		defer file.Close()
+
+		writer := bufio.NewWriter(file)
+		for fen := range fenChan {
+			_, _ = writer.WriteString(fen)
+		}
+		writer.Flush()
+		writeWG.Done()
+	}()
+
+	progress := pgn.NewProgressGroup(len(sources))
+	jobs := make(chan struct{}, max(parallel, 1))
+	var sourceWG sync.WaitGroup
+
 SourceLoop:
 	for _, path := range sources {
 		select {
 		case <-ctx.Done():
 			break SourceLoop
-		default:
+		case jobs <- struct{}{}:
 		}
-		pp, err := pgn.NewPGNParser(path, false)
+		pp, err := pgn.NewPGNParser(path, filter)
 		if err != nil {
 			fmt.Printf("could not load pgn file: %s with error: %s\n", path, err)
+			<-jobs
 			continue
 		}
+		pp.SetProgressGroup(progress)
 
-		fenChan := make(chan string)
-		pgnChan := make(chan *pgn.PGN, 20)
-		go func() {
-			for pp.Scan(ctx) {
-				pgnChan <- pp.PGN()
-			}
-			pp.Close()
-			pp.Progress(true)
-			close(pgnChan)
-		}()
-
-		var writeWG sync.WaitGroup
-		writeWG.Add(1)
-		go func() {
-			file, err := os.Create(outPath)
-			if err != nil {
-				fmt.Println("failed opening file for writing: ", path)
-			}
-			defer file.Close()
-
-			writer := bufio.NewWriter(file)
-			for fen := range fenChan {
-				_, _ = writer.WriteString(fen)
-			}
-			writer.Flush()
-			writeWG.Done()
-		}()
+		sourceWG.Add(1)
+		go func(pp *pgn.Parser) {
+			defer func() {
+				<-jobs
+				sourceWG.Done()
+			}()
 
-		var wg sync.WaitGroup
-		for game := range pgnChan {
-			wg.Add(1)
-			go func(game *pgn.PGN) {
-				fens := game.GetFENs()
-				for i := range fens {
-					fenChan <- fens[i]
+			pgnChan := make(chan *pgn.PGN, 20)
+			go func() {
+				for pp.Scan(ctx) {
+					pgnChan <- pp.PGN()
 				}
-				defer wg.Done()
-			}(game)
-		}
+				if err := pp.Err(); err != nil {
+					fmt.Printf("\ncould not read pgn with error: %s\n", err)
+				}
+				pp.Close()
+				pp.Progress(true)
+				close(pgnChan)
+			}()
 
-		fmt.Println()
-		wg.Wait()
-		close(fenChan)
-		writeWG.Wait()
+			var wg sync.WaitGroup
+			for game := range pgnChan {
+				wg.Add(1)
+				go func(game *pgn.PGN) {
+					fens := game.GetFENs()
+					for i := range fens {
+						fenChan <- fens[i]
+					}
+					defer wg.Done()
+				}(game)
+			}
+			wg.Wait()
+		}(pp)
 	}
+	sourceWG.Wait()
+	fmt.Println()
+	close(fenChan)
+	writeWG.Wait()
 	fmt.Printf("Book saved: %v\n", outPath)
 }
@@ -2,13 +2,11 @@ module github.com/likeawizard/polyglot-composer
 
 go 1.22
 
-require github.com/pkg/profile v1.7.0
-
 require (
-	github.com/dsnet/compress v0.0.1 // indirect
-	github.com/felixge/fgprof v0.9.3 // indirect
-	github.com/google/pprof v0.0.0-20211214055906-6f57359322fd // indirect
-	github.com/inhies/go-bytesize v0.0.0-20220417184213-4913239db9cf // indirect
-	github.com/klauspost/compress v1.15.12 // indirect
-	github.com/likeawizard/tofiks v1.3.0 // indirect
+	github.com/dsnet/compress v0.0.1
+	github.com/inhies/go-bytesize v0.0.0-20220417184213-4913239db9cf
+	github.com/klauspost/compress v1.15.12
+	github.com/likeawizard/tofiks v1.3.0
+	github.com/ulikunitz/xz v0.5.12
+	golang.org/x/text v0.14.0
 )
@@ -0,0 +1,103 @@
+package explorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/likeawizard/polyglot-composer/pkg/pgn"
+	"github.com/likeawizard/polyglot-composer/pkg/polyglot"
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+// Response modeled after the lichess opening explorer. Moves are ordered by descending weight.
+type Response struct {
+	FEN    string `json:"fen"`
+	Moves  []Move `json:"moves"`
+	Weight uint64 `json:"weight"`
+}
+
+type Move struct {
+	UCI    string  `json:"uci"`
+	Weight uint64  `json:"weight"`
+	Share  float64 `json:"share"`
+}
+
+// Server answers opening explorer queries against a loaded polyglot book.
+type Server struct {
+	book *polyglot.Book
+	mux  *http.ServeMux
+}
+
+func NewServer(book *polyglot.Book) *Server {
+	s := &Server{
+		book: book,
+		mux:  http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/book", s.handleBook)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Query the book by `fen` (defaults to the starting position) and an optional comma delimited list of UCI `moves` played from it.
+func (s *Server) handleBook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	fen := query.Get("fen")
+	if fen == "" {
+		fen = "startpos"
+	} else if err := pgn.ValidateFEN(fen); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b := board.NewBoard(fen)
+	if moves := query.Get("moves"); moves != "" {
+		for _, uci := range strings.Split(moves, ",") {
+			move, err := findMove(b, strings.TrimSpace(uci))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			b.MakeMove(move)
+		}
+	}
+
+	response := Response{FEN: b.ExportFEN(), Moves: make([]Move, 0)}
+	bookMoves := s.book.Probe(b)
+	for _, bookMove := range bookMoves {
+		response.Weight += bookMove.Weight
+	}
+	for _, bookMove := range bookMoves {
+		response.Moves = append(response.Moves, Move{
+			UCI:    bookMove.Move,
+			Weight: bookMove.Weight,
+			Share:  float64(bookMove.Weight) / float64(response.Weight),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Find the legal move matching the UCI notation. Castling is accepted both as king to target (e1g1) and polyglot king to rook (e1h1) moves.
+func findMove(b *board.Board, uci string) (board.Move, error) {
+	for _, move := range b.MoveGenLegal() {
+		if move.String() == uci || polyglot.MoveToPolyMove(move) == uci {
+			return move, nil
+		}
+	}
+
+	return 0, fmt.Errorf("illegal move: '%s'", uci)
+}
@@ -0,0 +1,69 @@
+package explorer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/likeawizard/polyglot-composer/pkg/polyglot"
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+func newTestServer() *Server {
+	book := polyglot.NewPolyglotBook()
+	start := board.NewBoard("startpos")
+	book.AddMove(polyglot.PolyZobrist(start), "e2e4", 30)
+	book.AddMove(polyglot.PolyZobrist(start), "d2d4", 10)
+
+	return NewServer(book)
+}
+
+func TestHandleBook(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		query  url.Values
+		status int
+		moves  []string
+	}{
+		{name: "start position", method: http.MethodGet, status: http.StatusOK, moves: []string{"e2e4", "d2d4"}},
+		{name: "start position fen", method: http.MethodGet, query: url.Values{"fen": {"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"}}, status: http.StatusOK, moves: []string{"e2e4", "d2d4"}},
+		{name: "position not in book", method: http.MethodGet, query: url.Values{"moves": {"e2e4"}}, status: http.StatusOK, moves: []string{}},
+		{name: "invalid fen", method: http.MethodGet, query: url.Values{"fen": {"not a fen"}}, status: http.StatusBadRequest},
+		{name: "rank too long", method: http.MethodGet, query: url.Values{"fen": {"rnbqkbnr/ppppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"}}, status: http.StatusBadRequest},
+		{name: "missing king", method: http.MethodGet, query: url.Values{"fen": {"8/8/8/8/8/8/8/K7 w - - 0 1"}}, status: http.StatusBadRequest},
+		{name: "illegal move", method: http.MethodGet, query: url.Values{"moves": {"e2e5"}}, status: http.StatusBadRequest},
+		{name: "method not allowed", method: http.MethodPost, status: http.StatusMethodNotAllowed},
+	}
+
+	s := newTestServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/book?"+tt.query.Encode(), nil)
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var response Response
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Moves) != len(tt.moves) {
+				t.Fatalf("moves = %+v, want %v", response.Moves, tt.moves)
+			}
+			for i, move := range response.Moves {
+				if move.UCI != tt.moves[i] {
+					t.Errorf("move %d = %s, want %s", i, move.UCI, tt.moves[i])
+				}
+			}
+		})
+	}
+}
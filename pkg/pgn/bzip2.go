@@ -1,15 +1,13 @@
 package pgn
 
 import (
-	"bufio"
-
 	"github.com/dsnet/compress/bzip2"
 	"github.com/inhies/go-bytesize"
 )
 
 type Bzip2PGN struct {
 	reader *bzip2.Reader
-	pgn    *bufio.Scanner
+	pgn    *LineReader
 	close  closeFn
 	path   string
 	size   bytesize.ByteSize
@@ -36,7 +34,7 @@ func (s *Bzip2PGN) Open() error {
 
 	s.size = size
 	s.close = close
-	s.pgn = bufio.NewScanner(bufio.NewReader(s.reader))
+	s.pgn = NewLineReader(s.reader)
 
 	return nil
 }
@@ -53,6 +51,10 @@ func (s *Bzip2PGN) Text() string {
 	return s.pgn.Text()
 }
 
+func (s *Bzip2PGN) Err() error {
+	return s.pgn.Err()
+}
+
 func (s *Bzip2PGN) Size() bytesize.ByteSize {
 	if s.reader.InputOffset > 0 {
 		return s.size * bytesize.ByteSize(s.reader.OutputOffset/s.reader.InputOffset)
@@ -0,0 +1,89 @@
+package pgn
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/dsnet/compress/bzip2"
+	"github.com/inhies/go-bytesize"
+)
+
+// Decodes the independent streams of a multi-stream bzip2 file (ie pbzip2 or lbzip2 output) in parallel.
+type ParallelBzip2PGN struct {
+	pgn          *LineReader
+	inputReader  *ByteCountingReader
+	outputReader *ByteCountingReader
+	parallel     *parallelReader
+	close        closeFn
+	path         string
+	size         bytesize.ByteSize
+	workers      int
+}
+
+func NewParallelBzip2PGN(path string, workers int) *ParallelBzip2PGN {
+	return &ParallelBzip2PGN{
+		path:    path,
+		workers: max(workers, 1),
+	}
+}
+
+func (s *ParallelBzip2PGN) Open() error {
+	reader, size, close, err := openSource(s.path)
+	if err != nil {
+		return err
+	}
+
+	// Wrap the file and the decoded output in ByteCountingReader to estimate the data size by output/input ratio
+	s.inputReader = &ByteCountingReader{reader: reader}
+	decode := func(stream []byte) ([]byte, error) {
+		r, err := bzip2.NewReader(bytes.NewReader(stream), nil)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+	}
+	fallback := func(r io.Reader) (io.Reader, error) {
+		decoder, err := bzip2.NewReader(r, nil)
+		if err != nil {
+			return nil, err
+		}
+		return decoder, nil
+	}
+	s.parallel = newParallelReader(newBzip2Streams(s.inputReader), decode, fallback, s.workers)
+	s.outputReader = &ByteCountingReader{reader: s.parallel}
+
+	s.close = close
+	s.size = size
+	s.pgn = NewLineReader(s.outputReader)
+
+	return nil
+}
+
+func (s *ParallelBzip2PGN) Close() error {
+	s.parallel.Close()
+	return s.close()
+}
+
+func (s *ParallelBzip2PGN) Scan() bool {
+	return s.pgn.Scan()
+}
+
+func (s *ParallelBzip2PGN) Text() string {
+	return s.pgn.Text()
+}
+
+func (s *ParallelBzip2PGN) Err() error {
+	return s.pgn.Err()
+}
+
+func (s *ParallelBzip2PGN) Size() bytesize.ByteSize {
+	if s.inputReader.bytesRead > 0 {
+		return s.size * (s.outputReader.bytesRead / s.inputReader.bytesRead)
+	}
+
+	return s.size
+}
+
+func (s *ParallelBzip2PGN) BytesRead() bytesize.ByteSize {
+	return s.outputReader.bytesRead
+}
@@ -0,0 +1,66 @@
+package pgn
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	CHARSET_AUTO         = "auto"
+	CHARSET_UTF8         = "utf-8"
+	CHARSET_LATIN1       = "latin-1"
+	CHARSET_WINDOWS_1252 = "windows-1252"
+)
+
+// Character encoding of PGN input, lines are transcoded to UTF-8 as they are read.
+// Auto keeps valid UTF-8 lines and decodes the others as Windows-1252, a superset of the printable Latin-1 characters.
+var Charset = CHARSET_AUTO
+
+// Accepts the common aliases of the supported charsets ie `utf8`, `iso-8859-1`, `cp1252`.
+func NormalizeCharset(name string) (string, error) {
+	switch strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToLower(name)) {
+	case "", "auto":
+		return CHARSET_AUTO, nil
+	case "utf8":
+		return CHARSET_UTF8, nil
+	case "latin1", "iso88591", "l1":
+		return CHARSET_LATIN1, nil
+	case "windows1252", "cp1252":
+		return CHARSET_WINDOWS_1252, nil
+	default:
+		return "", fmt.Errorf("unsupported charset: %s", name)
+	}
+}
+
+// Transcode a line read in Charset to UTF-8.
+func toUTF8(line []byte) string {
+	switch Charset {
+	case CHARSET_LATIN1:
+		return decodeCharmap(charmap.ISO8859_1, line)
+	case CHARSET_WINDOWS_1252:
+		return decodeCharmap(charmap.Windows1252, line)
+	case CHARSET_AUTO:
+		if !utf8.Valid(line) {
+			return decodeCharmap(charmap.Windows1252, line)
+		}
+	}
+
+	return string(line)
+}
+
+func decodeCharmap(cm *charmap.Charmap, line []byte) string {
+	var text strings.Builder
+	text.Grow(len(line) + len(line)/8)
+	for _, b := range line {
+		if b < utf8.RuneSelf {
+			text.WriteByte(b)
+		} else {
+			text.WriteRune(cm.DecodeByte(b))
+		}
+	}
+
+	return text.String()
+}
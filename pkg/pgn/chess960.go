@@ -0,0 +1,267 @@
+package pgn
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+const (
+	kingSide  = 0
+	queenSide = 1
+)
+
+// Chess960 keeps track of the castling rooks of a Chess960 game as the board is only aware of standard castling.
+// Castling moves are played by rebuilding the board from FEN.
+type Chess960 struct {
+	kings [2]int
+	// Castling rook squares [color][kingSide, queenSide], -1 when the right has been lost.
+	rooks [2][2]int
+}
+
+// Set up the tracker and the starting board of a Chess960 game. Castling rights can be in X-FEN (KQkq) or Shredder-FEN (HAha) notation.
+func NewChess960(fen string) (*Chess960, *board.Board, error) {
+	if fen == "" {
+		fen = START_FEN
+	}
+	if err := ValidateFEN(fen); err != nil {
+		return nil, nil, err
+	}
+
+	fields := strings.Fields(fen)
+	squares := fenToSquares(fields[0])
+	c := &Chess960{
+		kings: [2]int{-1, -1},
+		rooks: [2][2]int{{-1, -1}, {-1, -1}},
+	}
+	for file := 0; file < 8; file++ {
+		if squares[backRank(board.WHITE)+file] == 'K' {
+			c.kings[board.WHITE] = backRank(board.WHITE) + file
+		}
+		if squares[backRank(board.BLACK)+file] == 'k' {
+			c.kings[board.BLACK] = backRank(board.BLACK) + file
+		}
+	}
+
+	for _, r := range fields[2] {
+		color, rook := board.WHITE, 'R'
+		if r >= 'a' && r <= 'z' {
+			color, rook = board.BLACK, 'r'
+		}
+		king := c.kings[color]
+		if king < 0 {
+			continue
+		}
+		base, kingFile := backRank(color), king%8
+
+		switch r {
+		case 'K', 'k':
+			for file := 7; file > kingFile; file-- {
+				if squares[base+file] == byte(rook) {
+					c.rooks[color][kingSide] = base + file
+					break
+				}
+			}
+		case 'Q', 'q':
+			for file := 0; file < kingFile; file++ {
+				if squares[base+file] == byte(rook) {
+					c.rooks[color][queenSide] = base + file
+					break
+				}
+			}
+		case '-':
+		default:
+			file := int(r - 'A')
+			if color == board.BLACK {
+				file = int(r - 'a')
+			}
+			if file < 0 || file > 7 || squares[base+file] != byte(rook) {
+				continue
+			}
+			if file > kingFile {
+				c.rooks[color][kingSide] = base + file
+			} else {
+				c.rooks[color][queenSide] = base + file
+			}
+		}
+	}
+
+	fields[2] = c.fenCastling()
+	b := board.NewBoard(strings.Join(fields, " "))
+	b.CastlingRights = c.castlingRights()
+
+	return c, b, nil
+}
+
+// Play the SAN move on the board and return the resulting board with the move in polyglot notation. Castling is encoded as king captures rook.
+func (c *Chess960) Play(b *board.Board, san string) (*board.Board, string, error) {
+	switch san {
+	case "O-O":
+		return c.castle(b, kingSide)
+	case "O-O-O":
+		return c.castle(b, queenSide)
+	}
+
+	move, err := SANToMove(b, san)
+	if err != nil {
+		return b, "", err
+	}
+	b.MakeMove(move)
+	c.update(b)
+
+	return b, move.String(), nil
+}
+
+func (c *Chess960) castle(b *board.Board, wing int) (*board.Board, string, error) {
+	color := int(b.Side)
+	king, rook := c.kings[color], c.rooks[color][wing]
+	if king < 0 || rook < 0 {
+		return b, "", fmt.Errorf("no castling rights")
+	}
+
+	fields := strings.Fields(b.ExportFEN())
+	if len(fields) < 6 {
+		return b, "", fmt.Errorf("invalid fen: '%s'", b.ExportFEN())
+	}
+	squares := fenToSquares(fields[0])
+
+	base := backRank(color)
+	kingTo, rookTo := base+6, base+5
+	if wing == queenSide {
+		kingTo, rookTo = base+2, base+3
+	}
+	for _, sq := range [2]int{kingTo, rookTo} {
+		if sq != king && sq != rook && squares[sq] != 0 {
+			return b, "", fmt.Errorf("castling path blocked")
+		}
+	}
+
+	kingPiece, rookPiece := squares[king], squares[rook]
+	squares[king], squares[rook] = 0, 0
+	squares[kingTo], squares[rookTo] = kingPiece, rookPiece
+	c.rooks[color] = [2]int{-1, -1}
+
+	side, fullMove := "b", fields[5]
+	if color == board.BLACK {
+		side = "w"
+		if n, err := strconv.Atoi(fields[5]); err == nil {
+			fullMove = strconv.Itoa(n + 1)
+		}
+	}
+	halfMove := fields[4]
+	if n, err := strconv.Atoi(fields[4]); err == nil {
+		halfMove = strconv.Itoa(n + 1)
+	}
+
+	fen := strings.Join([]string{squaresToFEN(squares), side, c.fenCastling(), "-", halfMove, fullMove}, " ")
+	next := board.NewBoard(fen)
+	next.CastlingRights = c.castlingRights()
+
+	return next, squareName(king) + squareName(rook), nil
+}
+
+// Castling rights are lost once the king or the castling rook has left its starting square.
+func (c *Chess960) update(b *board.Board) {
+	for color := board.WHITE; color <= board.BLACK; color++ {
+		if c.kings[color] < 0 || b.Pieces[color][board.KINGS]&(1<<c.kings[color]) == 0 {
+			c.rooks[color] = [2]int{-1, -1}
+			continue
+		}
+		for wing, rook := range c.rooks[color] {
+			if rook >= 0 && b.Pieces[color][board.ROOKS]&(1<<rook) == 0 {
+				c.rooks[color][wing] = -1
+			}
+		}
+	}
+	b.CastlingRights = c.castlingRights()
+}
+
+func (c *Chess960) castlingRights() board.CastlingRights {
+	var cr board.CastlingRights
+	rights := [2][2]board.CastlingRights{{board.WOO, board.WOOO}, {board.BOO, board.BOOO}}
+	for color := range c.rooks {
+		for wing, rook := range c.rooks[color] {
+			if rook >= 0 {
+				cr |= rights[color][wing]
+			}
+		}
+	}
+
+	return cr
+}
+
+func (c *Chess960) fenCastling() string {
+	var sb strings.Builder
+	letters := [2][2]string{{"K", "Q"}, {"k", "q"}}
+	for color := range c.rooks {
+		for wing, rook := range c.rooks[color] {
+			if rook >= 0 {
+				sb.WriteString(letters[color][wing])
+			}
+		}
+	}
+	if sb.Len() == 0 {
+		return "-"
+	}
+
+	return sb.String()
+}
+
+// First square of the back rank of the color. Squares are indexed from a8 (0) to h1 (63).
+func backRank(color int) int {
+	if color == board.WHITE {
+		return 56
+	}
+
+	return 0
+}
+
+func squareName(sq int) string {
+	return fmt.Sprintf("%c%d", 'a'+sq%8, 8-sq/8)
+}
+
+func fenToSquares(placement string) [64]byte {
+	var squares [64]byte
+	sq := 0
+	for i := 0; i < len(placement) && sq < 64; i++ {
+		switch ch := placement[i]; {
+		case ch == '/':
+		case ch >= '1' && ch <= '8':
+			sq += int(ch - '0')
+		default:
+			squares[sq] = ch
+			sq++
+		}
+	}
+
+	return squares
+}
+
+func squaresToFEN(squares [64]byte) string {
+	var sb strings.Builder
+	for rank := 0; rank < 8; rank++ {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			piece := squares[8*rank+file]
+			if piece == 0 {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			sb.WriteByte(piece)
+		}
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+		if rank < 7 {
+			sb.WriteByte('/')
+		}
+	}
+
+	return sb.String()
+}
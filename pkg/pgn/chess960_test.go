@@ -0,0 +1,138 @@
+package pgn
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChess960CastlingRights(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		rooks [2][2]int
+	}{
+		{"x-fen", "bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN w KQkq - 0 1", [2][2]int{{62, 60}, {6, 4}}},
+		{"shredder-fen", "bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN w GEge - 0 1", [2][2]int{{62, 60}, {6, 4}}},
+		{"partial", "bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN w Ge - 0 1", [2][2]int{{62, -1}, {-1, 4}}},
+		{"none", "bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN w - - 0 1", [2][2]int{{-1, -1}, {-1, -1}}},
+		// X-FEN takes the outermost rook of the wing
+		{"outermost rook", "4k3/8/8/8/8/8/8/R1R1K1RR w KQ - 0 1", [2][2]int{{63, 56}, {-1, -1}}},
+		{"shredder inner rook", "4k3/8/8/8/8/8/8/R1R1K1RR w GC - 0 1", [2][2]int{{62, 58}, {-1, -1}}},
+		{"no rook on file", "4k3/8/8/8/8/8/8/R3K2R w B - 0 1", [2][2]int{{-1, -1}, {-1, -1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, err := NewChess960(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.rooks != tt.rooks {
+				t.Errorf("rooks = %v, want %v", c.rooks, tt.rooks)
+			}
+		})
+	}
+}
+
+func TestChess960InvalidFEN(t *testing.T) {
+	for _, fen := range []string{
+		"bqnbrkrn/pppppppp/9/8/8/8/PPPPPPPP/BQNBRKRN w KQkq - 0 1",
+		"bqnbrkrn/pppppppp/8/8/8/PPPPPPPP/BQNBRKRN w KQkq - 0 1",
+		"bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN w",
+	} {
+		if _, _, err := NewChess960(fen); err == nil {
+			t.Errorf("NewChess960(%q) accepted an invalid fen", fen)
+		}
+	}
+}
+
+func TestChess960Play(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		sans  []string
+		moves []string
+		// Placement and castling rights after the last move
+		want string
+		err  string
+	}{
+		{
+			name:  "king takes rook next to it",
+			fen:   "bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN w KQkq - 0 1",
+			sans:  []string{"O-O"},
+			moves: []string{"f1g1"},
+			want:  "bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRRKN kq",
+		},
+		{
+			name:  "queen side",
+			fen:   "4k3/8/8/8/8/8/8/1R3KR1 w KQ - 0 1",
+			sans:  []string{"O-O-O"},
+			moves: []string{"f1b1"},
+			want:  "4k3/8/8/8/8/8/8/2KR2R1 -",
+		},
+		{
+			name:  "black",
+			fen:   "1r3kr1/8/8/8/8/8/8/4K3 b kq - 0 1",
+			sans:  []string{"O-O"},
+			moves: []string{"f8g8"},
+			want:  "1r3rk1/8/8/8/8/8/8/4K3 -",
+		},
+		{
+			name:  "rook moved",
+			fen:   "4k3/8/8/8/8/8/8/1R3KR1 w KQ - 0 1",
+			sans:  []string{"Rg2", "Kd7", "Rg1", "Ke8", "O-O"},
+			moves: []string{"g1g2", "e8d7", "g2g1", "d7e8"},
+			err:   "no castling rights",
+		},
+		{
+			name:  "other wing kept",
+			fen:   "4k3/8/8/8/8/8/8/1R3KR1 w KQ - 0 1",
+			sans:  []string{"Rg2", "Kd7", "O-O-O"},
+			moves: []string{"g1g2", "e8d7", "f1b1"},
+			want:  "8/3k4/8/8/8/8/6R1/2KR4 -",
+		},
+		{
+			name:  "king moved",
+			fen:   "4k3/8/8/8/8/8/8/1R3KR1 w KQ - 0 1",
+			sans:  []string{"Ke2", "Kd7", "Kf1", "Ke8", "O-O-O"},
+			moves: []string{"f1e2", "e8d7", "e2f1", "d7e8"},
+			err:   "no castling rights",
+		},
+		{
+			name: "blocked",
+			fen:  "4k3/8/8/8/8/8/8/1RB2KR1 w KQ - 0 1",
+			sans: []string{"O-O-O"},
+			err:  "castling path blocked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, b, err := NewChess960(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var moves []string
+			for _, san := range tt.sans {
+				var move string
+				if b, move, err = c.Play(b, san); err != nil {
+					break
+				}
+				moves = append(moves, move)
+			}
+			if (err != nil) != (tt.err != "") || (err != nil && !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+			if !slices.Equal(moves, tt.moves) {
+				t.Errorf("moves = %q, want %q", moves, tt.moves)
+			}
+			if tt.want == "" {
+				return
+			}
+			if got := strings.Fields(b.ExportFEN())[0] + " " + c.fenCastling(); got != tt.want {
+				t.Errorf("position = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package pgn
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/inhies/go-bytesize"
+)
+
+const CHESSCOM_URL = "https://api.chess.com"
+
+type ChessComOptions struct {
+	// Defaults to CHESSCOM_URL
+	BaseURL string
+	// Only monthly archives overlapping the time range from Since up to but excluding Until, ignored if zero
+	Since time.Time
+	Until time.Time
+}
+
+// Streams a user's games from the chess.com monthly archives, one month after another.
+type ChessComPGN struct {
+	pgn       *LineReader
+	reader    *ByteCountingReader
+	err       error
+	close     closeFn
+	opts      ChessComOptions
+	user      string
+	archives  []string
+	next      int
+	bytesRead bytesize.ByteSize
+}
+
+func NewChessComPGN(user string, opts ChessComOptions) *ChessComPGN {
+	if opts.BaseURL == "" {
+		opts.BaseURL = CHESSCOM_URL
+	}
+
+	return &ChessComPGN{
+		user: user,
+		opts: opts,
+	}
+}
+
+// Open fetches the list of monthly archives. The archives are opened one by one while scanning.
+func (s *ChessComPGN) Open() error {
+	base, err := url.Parse(strings.TrimSuffix(s.opts.BaseURL, "/") + "/")
+	if err != nil {
+		return err
+	}
+	listURL := base.JoinPath("pub", "player", strings.ToLower(s.user), "games", "archives")
+
+	r, err := chessComGet(listURL.String())
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	var list struct {
+		Archives []string `json:"archives"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		return fmt.Errorf("could not decode chess.com archives for '%s': %w", s.user, err)
+	}
+
+	s.archives = make([]string, 0, len(list.Archives))
+	for _, archive := range list.Archives {
+		u, err := base.Parse(archive)
+		if err != nil {
+			continue
+		}
+		if s.inRange(u.Path) {
+			s.archives = append(s.archives, u.String())
+		}
+	}
+	s.close = func() error { return nil }
+
+	return nil
+}
+
+// Archives end in `/YYYY/MM`.
+func (s *ChessComPGN) inRange(path string) bool {
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) < 2 {
+		return true
+	}
+	month, err := time.Parse("2006/01", strings.Join(parts[len(parts)-2:], "/"))
+	if err != nil {
+		return true
+	}
+
+	return (s.opts.Since.IsZero() || month.AddDate(0, 1, 0).After(s.opts.Since)) && (s.opts.Until.IsZero() || month.Before(s.opts.Until))
+}
+
+func (s *ChessComPGN) openNext() bool {
+	for s.next < len(s.archives) {
+		archive := s.archives[s.next]
+		s.next++
+		if s.reader != nil {
+			s.bytesRead += s.reader.bytesRead
+			_ = s.close()
+		}
+
+		r, err := chessComGet(archive + "/pgn")
+		if err != nil {
+			log.Printf("chess.com archive: %s error: %s\n", archive, err)
+			s.reader = nil
+			continue
+		}
+
+		s.reader = &ByteCountingReader{reader: r.Body}
+		s.close = r.Body.Close
+		s.keepErr()
+		s.pgn = NewLineReader(s.reader)
+		return true
+	}
+
+	return false
+}
+
+func chessComGet(u string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Requests without a user agent may be rejected
+	req.Header.Set("User-Agent", "polyglot-composer")
+
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		_ = r.Body.Close()
+		return nil, fmt.Errorf("request '%s' failed: %s", u, r.Status)
+	}
+
+	return r, nil
+}
+
+// Keep the read error of the current archive before it is replaced.
+func (s *ChessComPGN) keepErr() {
+	if s.err == nil && s.pgn != nil {
+		s.err = s.pgn.Err()
+	}
+}
+
+func (s *ChessComPGN) Close() error {
+	return s.close()
+}
+
+func (s *ChessComPGN) Scan() bool {
+	for {
+		if s.pgn != nil && s.pgn.Scan() {
+			return true
+		}
+		if !s.openNext() {
+			return false
+		}
+	}
+}
+
+func (s *ChessComPGN) Text() string {
+	return s.pgn.Text()
+}
+
+// The first read error of all archives, nil before the first one is opened.
+func (s *ChessComPGN) Err() error {
+	if s.err != nil || s.pgn == nil {
+		return s.err
+	}
+
+	return s.pgn.Err()
+}
+
+// Estimated from the average size of the archives read so far.
+func (s *ChessComPGN) Size() bytesize.ByteSize {
+	if s.next <= 1 || len(s.archives) == 0 {
+		return 0
+	}
+
+	return s.bytesRead / bytesize.ByteSize(s.next-1) * bytesize.ByteSize(len(s.archives))
+}
+
+func (s *ChessComPGN) BytesRead() bytesize.ByteSize {
+	if s.reader == nil {
+		return s.bytesRead
+	}
+
+	return s.bytesRead + s.reader.bytesRead
+}
@@ -0,0 +1,167 @@
+package pgn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Serves the archives of `user` and the month games, missing months fail with 500.
+func newChessComServer(t *testing.T, months map[string]string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") == "" {
+			t.Error("request without user agent")
+		}
+		const prefix = "/pub/player/user/games/"
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			http.NotFound(w, r)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, prefix)
+		if path == "archives" {
+			list := struct {
+				Archives []string `json:"archives"`
+			}{Archives: []string{}}
+			for _, month := range []string{"2023/12", "2024/01", "2024/02", "2024/03"} {
+				list.Archives = append(list.Archives, server.URL+prefix+month)
+			}
+			_ = json.NewEncoder(w).Encode(list)
+			return
+		}
+		games, ok := months[strings.TrimSuffix(path, "/pgn")]
+		if !ok {
+			http.Error(w, "archive unavailable", http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(games))
+	}))
+
+	return server
+}
+
+func TestChessComArchives(t *testing.T) {
+	months := map[string]string{
+		"2023/12": "1. e4 1-0\n",
+		"2024/01": "1. d4 0-1\n",
+		"2024/02": "1. c4 1/2-1/2\n",
+		"2024/03": "1. Nf3 1-0\n",
+	}
+	tests := []struct {
+		name  string
+		opts  ChessComOptions
+		lines []string
+	}{
+		{name: "all months", lines: []string{"1. e4 1-0", "1. d4 0-1", "1. c4 1/2-1/2", "1. Nf3 1-0"}},
+		{name: "since", opts: ChessComOptions{Since: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)}, lines: []string{"1. c4 1/2-1/2", "1. Nf3 1-0"}},
+		{name: "until", opts: ChessComOptions{Until: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)}, lines: []string{"1. e4 1-0", "1. d4 0-1"}},
+		{name: "since first of month", opts: ChessComOptions{Since: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}, lines: []string{"1. c4 1/2-1/2", "1. Nf3 1-0"}},
+		{name: "until first of month", opts: ChessComOptions{Until: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}, lines: []string{"1. e4 1-0", "1. d4 0-1"}},
+	}
+
+	server := newChessComServer(t, months)
+	defer server.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.BaseURL = server.URL
+			s := NewChessComPGN("User", tt.opts)
+			if err := s.Open(); err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			if lines := readLines(t, s); !slices.Equal(lines, tt.lines) {
+				t.Errorf("lines = %q, want %q", lines, tt.lines)
+			}
+		})
+	}
+}
+
+func TestChessComUnavailableMonth(t *testing.T) {
+	server := newChessComServer(t, map[string]string{"2024/01": "1. d4 0-1\n", "2024/03": "1. Nf3 1-0\n"})
+	defer server.Close()
+
+	s := NewChessComPGN("user", ChessComOptions{BaseURL: server.URL})
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	want := []string{"1. d4 0-1", "1. Nf3 1-0"}
+	if lines := readLines(t, s); !slices.Equal(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
+
+func TestChessComUnknownUser(t *testing.T) {
+	server := newChessComServer(t, nil)
+	defer server.Close()
+
+	s := NewChessComPGN("nobody", ChessComOptions{BaseURL: server.URL})
+	if err := s.Open(); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("err = %v, want a 404 error", err)
+	}
+}
+
+func TestChessComNoArchives(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"archives":[]}`))
+	}))
+	defer server.Close()
+
+	pp, err := NewPGNParser(NewChessComPGN("user", ChessComOptions{BaseURL: server.URL}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pp.Close()
+
+	if pp.Scan(context.Background()) {
+		t.Errorf("scanned a game without archives")
+	}
+	if err := pp.Err(); err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+}
+
+func TestChessComKeepsFirstError(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pub/player/user/games/archives":
+			_, _ = fmt.Fprintf(w, `{"archives":["%[1]s/2024/01","%[1]s/2024/02"]}`, server.URL)
+		case "/2024/01/pgn":
+			// The connection is closed before the announced length is sent
+			w.Header().Set("Content-Length", "1000")
+			_, _ = w.Write([]byte("1. e4 1-0\n"))
+		default:
+			_, _ = w.Write([]byte("1. d4 0-1\n"))
+		}
+	}))
+	defer server.Close()
+
+	s := NewChessComPGN("user", ChessComOptions{BaseURL: server.URL})
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var lines []string
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	if err := s.Err(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if want := []string{"1. e4 1-0", "1. d4 0-1"}; !slices.Equal(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
@@ -0,0 +1,238 @@
+package pgn
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/inhies/go-bytesize"
+)
+
+// Number of chunks plain PGN files are split into to be parsed concurrently. Files are not split when 1.
+var SplitChunks = 1
+
+// Reads the games of a plain PGN file whose tag section starts within the byte range [start, end).
+// Chunks re-synchronize to the first tag section after start and read past end to complete the last game,
+// so that splitting a file into consecutive ranges yields the same games as reading it sequentially.
+// Brace comments are tracked like the parser does, a chunk starting inside a comment tells so from the last brace before it.
+type ChunkPGN struct {
+	pgn  *LineReader
+	file *os.File
+	path string
+	// Offset reading started at
+	base      int64
+	start     int64
+	end       int64
+	offset    int64
+	lineStart int64
+	started   bool
+	done      bool
+	// Whether the last non-blank line was a tag, a tag line following anything else starts a game
+	inTags bool
+	// Inside a brace comment spanning lines, tag lines within are movetext
+	inComment bool
+}
+
+func NewChunkPGN(path string, start, end int64) *ChunkPGN {
+	return &ChunkPGN{
+		path:  path,
+		start: start,
+		end:   end,
+	}
+}
+
+// Split a plain PGN file into consecutive chunks of about equal size.
+func SplitPlainPGN(path string, chunks int) ([]Source, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	size := stat.Size()
+	n := max(1, min(int64(chunks), size))
+	sources := make([]Source, 0, n)
+	for i := int64(0); i < n; i++ {
+		sources = append(sources, NewChunkPGN(path, i*size/n, (i+1)*size/n))
+	}
+
+	return sources, nil
+}
+
+func (s *ChunkPGN) Open() error {
+	var err error
+	s.file, err = os.Open(s.path)
+	if err != nil {
+		return err
+	}
+
+	// Start a byte early to tell whether start is at the beginning of a line. The first (partial) line belongs to the previous chunk.
+	s.offset = max(s.start-1, 0)
+	s.started = s.start == 0
+	if _, err := s.file.Seek(s.offset, io.SeekStart); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+
+	s.base = s.offset
+	s.pgn = NewLineReader(s.file)
+	if !s.started {
+		s.scanLine()
+		s.inTags, err = tagSectionAt(s.file, s.offset)
+		if err == nil {
+			s.inComment, err = commentAt(s.file, s.offset)
+		}
+		if err != nil {
+			_ = s.file.Close()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Whether the last non-blank line before offset that is not an escape line is a tag, ie a tag line at offset continues the tag section of a game.
+func tagSectionAt(file *os.File, offset int64) (bool, error) {
+	buf := make([]byte, 4096)
+	var tail string
+	for offset > 0 {
+		n := min(int64(len(buf)), offset)
+		offset -= n
+		if _, err := file.ReadAt(buf[:n], offset); err != nil {
+			return false, err
+		}
+		lines := strings.Split(string(buf[:n])+tail, "\n")
+
+		// The first line is incomplete unless it starts the file
+		complete := lines[1:]
+		if offset == 0 {
+			complete = lines
+		}
+		for i := len(complete) - 1; i >= 0; i-- {
+			// Escape lines are skipped by the parser
+			if strings.TrimSpace(complete[i]) != "" && !strings.HasPrefix(complete[i], "%") {
+				return isTag(complete[i]), nil
+			}
+		}
+		tail = lines[0]
+	}
+
+	return false, nil
+}
+
+// Comments longer than this are not expected, the scan for an open comment gives up after as many bytes without a brace.
+const commentScanLimit = 1 << 20
+
+// Whether offset is inside a brace comment, judged by the last movetext line with a brace before it.
+func commentAt(file *os.File, offset int64) (bool, error) {
+	buf := make([]byte, 4096)
+	var tail string
+	limit := max(offset-commentScanLimit, 0)
+	for offset > limit {
+		n := min(int64(len(buf)), offset-limit)
+		offset -= n
+		if _, err := file.ReadAt(buf[:n], offset); err != nil {
+			return false, err
+		}
+		lines := strings.Split(string(buf[:n])+tail, "\n")
+
+		// The first line is incomplete unless it starts the file
+		complete := lines[1:]
+		if offset == 0 {
+			complete = lines
+		}
+		for i := len(complete) - 1; i >= 0; i-- {
+			if isTag(complete[i]) || strings.HasPrefix(complete[i], "%") {
+				continue
+			}
+			if inComment, ok := braceState(complete[i]); ok {
+				return inComment, nil
+			}
+		}
+		tail = lines[0]
+	}
+
+	return false, nil
+}
+
+// Whether a brace comment is open at the end of the line, ok is false when the line has no braces outside `;` comments.
+// A closing brace without an opening one ends a comment started on an earlier line.
+func braceState(line string) (inComment, ok bool) {
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; {
+		case inComment:
+			inComment = ch != '}'
+		case ch == '{':
+			inComment, ok = true, true
+		case ch == '}':
+			ok = true
+		case ch == ';':
+			i = len(line)
+		}
+	}
+
+	return inComment, ok
+}
+
+// Scan the next line keeping track of its byte offset.
+func (s *ChunkPGN) scanLine() bool {
+	s.lineStart = s.base + s.pgn.consumed
+	ok := s.pgn.Scan()
+	s.offset = s.base + s.pgn.consumed
+
+	return ok
+}
+
+func (s *ChunkPGN) Close() error {
+	return s.file.Close()
+}
+
+func (s *ChunkPGN) Scan() bool {
+	for !s.done && s.scanLine() {
+		line := s.pgn.Text()
+		gameStart := false
+		switch {
+		case strings.TrimSpace(line) == "":
+		case !s.inComment && strings.HasPrefix(line, "%"):
+			// Escape lines are skipped by the parser
+		case !s.inComment && isTag(line):
+			gameStart = !s.inTags
+			s.inTags = true
+		default:
+			// The parser starts over outside a comment once the game ended
+			var ends bool
+			ends, s.inComment = scanMovetext(line, s.inComment)
+			s.inComment = s.inComment && !ends
+			s.inTags = false
+		}
+		if !s.started {
+			if !gameStart {
+				continue
+			}
+			s.started = true
+		}
+		if gameStart && s.lineStart >= s.end {
+			s.done = true
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (s *ChunkPGN) Text() string {
+	return s.pgn.Text()
+}
+
+func (s *ChunkPGN) Err() error {
+	return s.pgn.Err()
+}
+
+func (s *ChunkPGN) Size() bytesize.ByteSize {
+	return bytesize.ByteSize(s.end - s.start)
+}
+
+func (s *ChunkPGN) BytesRead() bytesize.ByteSize {
+	return bytesize.ByteSize(min(max(s.offset-s.start, 0), s.end-s.start))
+}
@@ -0,0 +1,89 @@
+package pgn
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Games with comments spanning lines that hold tag pairs and results, `;` comments and escape lines.
+func writeChunkGames(t *testing.T, games int) string {
+	t.Helper()
+	var sb strings.Builder
+	for i := 0; i < games; i++ {
+		fmt.Fprintf(&sb, "[Event \"Game %d\"]\n[Result \"1-0\"]\n\n", i)
+		switch i % 5 {
+		case 0:
+			sb.WriteString("1. e4 e5 1-0\n\n")
+		case 1:
+			sb.WriteString("1. e4 {a comment\n[Event \"Quoted\"]\n[Result \"0-1\"]\n\nspanning lines 0-1\n} e5 1-0\n\n")
+		case 2:
+			sb.WriteString("1. d4 ; not a {comment\nd5 {\n\n[Site \"?\"]\n} 1-0\n\n")
+		case 3:
+			sb.WriteString("%escape line\n1. c4 {one} {two\n[Event \"Inner\"]\n} c5 {three}\n1-0\n\n")
+		default:
+			sb.WriteString("1. Nf3 {a long comment\n")
+			for j := 0; j < 20; j++ {
+				fmt.Fprintf(&sb, "[Event \"Inner %d\"] 1-0\n", j)
+			}
+			sb.WriteString("} 1-0\n\n")
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "games.pgn")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func readGames(t *testing.T, source Source) []string {
+	t.Helper()
+	pp, err := NewPGNParser(source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pp.Close()
+
+	var games []string
+	for pp.Scan(context.Background()) {
+		games = append(games, pp.PGN().Tag(TAG_EVENT)+"\n"+pp.PGN().Moves)
+	}
+	if err := pp.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return games
+}
+
+func TestSplitPlainPGN(t *testing.T) {
+	path := writeChunkGames(t, 200)
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	want := readGames(t, NewReaderPGN(file))
+	if len(want) != 200 {
+		t.Fatalf("games = %d, want 200", len(want))
+	}
+
+	for _, chunks := range []int{1, 2, 3, 7, 16, 50, 199, 1000} {
+		sources, err := SplitPlainPGN(path, chunks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var games []string
+		for _, source := range sources {
+			games = append(games, readGames(t, source)...)
+		}
+		if !slices.Equal(games, want) {
+			t.Errorf("%d chunks: read %d games, want the %d games of the sequential scan", chunks, len(games), len(want))
+		}
+	}
+}
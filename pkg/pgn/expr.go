@@ -0,0 +1,714 @@
+package pgn
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A compiled filter expression selecting games by their tags, ie
+//
+//	WhiteElo >= 2400 && abs(WhiteElo - BlackElo) < 200 && TimeControl in [rapid, classical] && Event !~ "Bullet"
+//
+// Capitalized identifiers are tags, missing tags are empty. Lowercase identifiers are functions when called and plain words otherwise.
+// Values are compared as numbers when both sides are numbers and as strings when both are strings.
+// TimeControl also equals its class (bullet, blitz, rapid, classical, correspondence) and Variant its normalized name.
+//
+// Operators by precedence: `||` `or`, `&&` `and`, `!` `not`, comparisons `==` `!=` `<` `<=` `>` `>=` `in [...]` and regexp
+// matches `=~` `!~`, `+` `-`, `*` `/`. Functions: abs, min, max, lower, upper.
+// Strings are quoted with `"` or `'`, a backslash only escapes the quote so that regular expressions keep their escapes.
+type Expr struct {
+	root exprNode
+	src  string
+}
+
+// Error of a filter expression pointing to the offending column.
+type ExprError struct {
+	Expr string
+	Msg  string
+	// Byte offset in Expr
+	Pos int
+}
+
+func (e *ExprError) Error() string {
+	column := utf8.RuneCountInString(e.Expr[:min(e.Pos, len(e.Expr))])
+	return fmt.Sprintf("filter expression: %s at column %d\n\t%s\n\t%s^", e.Msg, column+1, e.Expr, strings.Repeat(" ", column))
+}
+
+func CompileExpr(src string) (*Expr, error) {
+	tokens, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{src: src, tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != exprEOF {
+		return nil, p.errorf(t, "unexpected '%s'", t.text)
+	}
+
+	return &Expr{src: src, root: root}, nil
+}
+
+// Whether the game is selected by the expression.
+func (e *Expr) Match(pgn *PGN) bool {
+	return e.root.eval(pgn).truthy()
+}
+
+// Expressions are evaluated on the complete game.
+func (e *Expr) MatchTag(_ Tag, _ string) bool {
+	return true
+}
+
+func (e *Expr) MatchGame(pgn *PGN) bool {
+	return e.Match(pgn)
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+type valueKind int
+
+const (
+	kindUndefined valueKind = iota
+	kindBool
+	kindNumber
+	kindString
+	kindList
+)
+
+type exprValue struct {
+	str string
+	// Alternative string values ie the class of a time control
+	aliases []string
+	list    []exprValue
+	num     float64
+	kind    valueKind
+	b       bool
+}
+
+func boolValue(b bool) exprValue {
+	return exprValue{kind: kindBool, b: b}
+}
+
+func (v exprValue) number() (float64, bool) {
+	switch v.kind {
+	case kindNumber:
+		return v.num, true
+	case kindString:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v.str), 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func (v exprValue) strings() []string {
+	switch v.kind {
+	case kindNumber:
+		return []string{strconv.FormatFloat(v.num, 'f', -1, 64)}
+	case kindString:
+		return append([]string{v.str}, v.aliases...)
+	default:
+		return nil
+	}
+}
+
+func (v exprValue) truthy() bool {
+	switch v.kind {
+	case kindBool:
+		return v.b
+	case kindNumber:
+		return v.num != 0
+	case kindString:
+		return v.str != ""
+	case kindList:
+		return len(v.list) > 0
+	default:
+		return false
+	}
+}
+
+// Compare as numbers if both are numbers and as strings if both are strings. The second result is false if the values are not comparable.
+func compareValues(a, b exprValue) (int, bool) {
+	if x, ok := a.number(); ok {
+		if y, ok := b.number(); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+	}
+	// A number is not comparable to a string that is not a number ie an unknown rating `?`
+	if a.kind != kindString || b.kind != kindString {
+		return 0, false
+	}
+
+	return strings.Compare(a.str, b.str), true
+}
+
+func equalValues(a, b exprValue) bool {
+	if cmp, ok := compareValues(a, b); ok && cmp == 0 {
+		return true
+	}
+	if a.kind == kindBool && b.kind == kindBool {
+		return a.b == b.b
+	}
+	for _, x := range a.strings() {
+		for _, y := range b.strings() {
+			if strings.EqualFold(x, y) && (len(a.aliases) > 0 || len(b.aliases) > 0) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type exprNode interface {
+	eval(pgn *PGN) exprValue
+}
+
+type literalNode struct {
+	value exprValue
+}
+
+func (n literalNode) eval(_ *PGN) exprValue {
+	return n.value
+}
+
+type tagNode struct {
+	tag Tag
+}
+
+func (n tagNode) eval(pgn *PGN) exprValue {
+	value := exprValue{kind: kindString, str: pgn.Tag(n.tag)}
+	switch n.tag {
+	case TAG_TIMECONTROL:
+		if class := TimeControlClass(value.str); class != "" {
+			value.aliases = []string{class}
+		}
+	case TAG_VARIANT:
+		value.aliases = []string{pgn.Variant()}
+	}
+
+	return value
+}
+
+type listNode struct {
+	items []exprNode
+}
+
+func (n listNode) eval(pgn *PGN) exprValue {
+	value := exprValue{kind: kindList, list: make([]exprValue, len(n.items))}
+	for i, item := range n.items {
+		value.list[i] = item.eval(pgn)
+	}
+
+	return value
+}
+
+type notNode struct {
+	operand exprNode
+}
+
+func (n notNode) eval(pgn *PGN) exprValue {
+	return boolValue(!n.operand.eval(pgn).truthy())
+}
+
+type negNode struct {
+	operand exprNode
+}
+
+func (n negNode) eval(pgn *PGN) exprValue {
+	x, ok := n.operand.eval(pgn).number()
+	if !ok {
+		return exprValue{}
+	}
+
+	return exprValue{kind: kindNumber, num: -x}
+}
+
+type binaryNode struct {
+	left  exprNode
+	right exprNode
+	op    string
+}
+
+func (n binaryNode) eval(pgn *PGN) exprValue {
+	switch n.op {
+	case "&&":
+		return boolValue(n.left.eval(pgn).truthy() && n.right.eval(pgn).truthy())
+	case "||":
+		return boolValue(n.left.eval(pgn).truthy() || n.right.eval(pgn).truthy())
+	}
+
+	left, right := n.left.eval(pgn), n.right.eval(pgn)
+	switch n.op {
+	case "==":
+		return boolValue(equalValues(left, right))
+	case "!=":
+		return boolValue(!equalValues(left, right))
+	case "in":
+		for _, item := range right.list {
+			if equalValues(left, item) {
+				return boolValue(true)
+			}
+		}
+		return boolValue(false)
+	case "<", "<=", ">", ">=":
+		cmp, ok := compareValues(left, right)
+		if !ok {
+			return boolValue(false)
+		}
+		return boolValue(map[string]bool{"<": cmp < 0, "<=": cmp <= 0, ">": cmp > 0, ">=": cmp >= 0}[n.op])
+	}
+
+	x, ok := left.number()
+	y, ok2 := right.number()
+	if !ok || !ok2 {
+		return exprValue{}
+	}
+	switch n.op {
+	case "+":
+		return exprValue{kind: kindNumber, num: x + y}
+	case "-":
+		return exprValue{kind: kindNumber, num: x - y}
+	case "*":
+		return exprValue{kind: kindNumber, num: x * y}
+	case "/":
+		if y == 0 {
+			return exprValue{}
+		}
+		return exprValue{kind: kindNumber, num: x / y}
+	}
+
+	return exprValue{}
+}
+
+type matchNode struct {
+	left   exprNode
+	re     *regexp.Regexp
+	negate bool
+}
+
+func (n matchNode) eval(pgn *PGN) exprValue {
+	matched := false
+	for _, s := range n.left.eval(pgn).strings() {
+		matched = matched || n.re.MatchString(s)
+	}
+
+	return boolValue(matched != n.negate)
+}
+
+type callNode struct {
+	fn   func(args []exprValue) exprValue
+	args []exprNode
+}
+
+func (n callNode) eval(pgn *PGN) exprValue {
+	args := make([]exprValue, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.eval(pgn)
+	}
+
+	return n.fn(args)
+}
+
+type exprFunc struct {
+	fn      func(args []exprValue) exprValue
+	minArgs int
+	maxArgs int
+}
+
+func numberFunc(fn func(nums []float64) float64) func(args []exprValue) exprValue {
+	return func(args []exprValue) exprValue {
+		nums := make([]float64, len(args))
+		for i, arg := range args {
+			var ok bool
+			if nums[i], ok = arg.number(); !ok {
+				return exprValue{}
+			}
+		}
+		return exprValue{kind: kindNumber, num: fn(nums)}
+	}
+}
+
+func stringFunc(fn func(string) string) func(args []exprValue) exprValue {
+	return func(args []exprValue) exprValue {
+		if args[0].kind != kindString && args[0].kind != kindNumber {
+			return exprValue{}
+		}
+		return exprValue{kind: kindString, str: fn(args[0].strings()[0])}
+	}
+}
+
+var exprFuncs = map[string]exprFunc{
+	"abs":   {fn: numberFunc(func(n []float64) float64 { return math.Abs(n[0]) }), minArgs: 1, maxArgs: 1},
+	"min":   {fn: numberFunc(func(n []float64) float64 { return slices.Min(n) }), minArgs: 2, maxArgs: math.MaxInt},
+	"max":   {fn: numberFunc(func(n []float64) float64 { return slices.Max(n) }), minArgs: 2, maxArgs: math.MaxInt},
+	"lower": {fn: stringFunc(strings.ToLower), minArgs: 1, maxArgs: 1},
+	"upper": {fn: stringFunc(strings.ToUpper), minArgs: 1, maxArgs: 1},
+}
+
+type exprTokenKind int
+
+const (
+	exprEOF exprTokenKind = iota
+	exprIdent
+	exprNumber
+	exprString
+	exprOp
+)
+
+type exprToken struct {
+	text  string
+	kind  exprTokenKind
+	pos   int
+	value float64
+}
+
+var exprOps = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "+", "-", "*", "/", "(", ")", "[", "]", ","}
+
+func lexExpr(src string) ([]exprToken, error) {
+	tokens := make([]exprToken, 0)
+	for i := 0; i < len(src); {
+		ch, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(ch):
+			i += size
+		case ch == '"' || ch == '\'':
+			// Only escaped quotes are unescaped so that regular expressions keep their escapes ie `\d`
+			var text strings.Builder
+			end := i + 1
+			for ; end < len(src) && rune(src[end]) != ch; end++ {
+				if src[end] == '\\' && end+1 < len(src) && rune(src[end+1]) == ch {
+					end++
+				}
+				text.WriteByte(src[end])
+			}
+			if end >= len(src) {
+				return nil, &ExprError{Expr: src, Pos: i, Msg: "unterminated string"}
+			}
+			tokens = append(tokens, exprToken{kind: exprString, text: text.String(), pos: i})
+			i = end + 1
+		case ch >= '0' && ch <= '9' || ch == '.':
+			end := i
+			for end < len(src) && (src[end] >= '0' && src[end] <= '9' || src[end] == '.') {
+				end++
+			}
+			n, err := strconv.ParseFloat(src[i:end], 64)
+			if err != nil {
+				return nil, &ExprError{Expr: src, Pos: i, Msg: fmt.Sprintf("invalid number '%s'", src[i:end])}
+			}
+			tokens = append(tokens, exprToken{kind: exprNumber, text: src[i:end], value: n, pos: i})
+			i = end
+		case ch == '_' || unicode.IsLetter(ch):
+			end := i
+			for end < len(src) {
+				r, size := utf8.DecodeRuneInString(src[end:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, exprToken{kind: exprIdent, text: src[i:end], pos: i})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range exprOps {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" && ch == '=' {
+				return nil, &ExprError{Expr: src, Pos: i, Msg: "unexpected '=', use '==' to compare"}
+			}
+			if op == "" {
+				return nil, &ExprError{Expr: src, Pos: i, Msg: fmt.Sprintf("unexpected character '%c'", ch)}
+			}
+			tokens = append(tokens, exprToken{kind: exprOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, exprToken{kind: exprEOF, text: "end of expression", pos: len(src)}), nil
+}
+
+// Recursive descent parser, one method per precedence level.
+type exprParser struct {
+	src    string
+	tokens []exprToken
+	next   int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.next]
+}
+
+func (p *exprParser) advance() exprToken {
+	t := p.tokens[p.next]
+	if t.kind != exprEOF {
+		p.next++
+	}
+
+	return t
+}
+
+// Consume the token if it is one of the operators or keywords.
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	for _, op := range ops {
+		if (t.kind == exprOp || t.kind == exprIdent) && t.text == op {
+			p.advance()
+			return op, true
+		}
+	}
+
+	return "", false
+}
+
+func (p *exprParser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		t := p.peek()
+		return p.errorf(t, "expected '%s' but found '%s'", op, t.text)
+	}
+
+	return nil
+}
+
+func (p *exprParser) errorf(t exprToken, format string, args ...any) error {
+	return &ExprError{Expr: p.src, Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "||", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">", "=~", "!~", "in")
+	if !ok {
+		return left, nil
+	}
+
+	switch op {
+	case "=~", "!~":
+		t := p.advance()
+		if t.kind != exprString {
+			return nil, p.errorf(t, "'%s' expects a quoted regular expression but found '%s'", op, t.text)
+		}
+		re, err := regexp.Compile(t.text)
+		if err != nil {
+			return nil, p.errorf(t, "invalid regular expression: %s", err)
+		}
+		return matchNode{left: left, re: re, negate: op == "!~"}, nil
+	case "in":
+		if t := p.peek(); t.kind != exprOp || t.text != "[" {
+			return nil, p.errorf(t, "'in' expects a list ie [a, b] but found '%s'", t.text)
+		}
+	}
+
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == exprOp && strings.ContainsAny(t.text, "<>=~") {
+		return nil, p.errorf(t, "comparisons cannot be chained, combine them with &&")
+	}
+
+	return binaryNode{op: op, left: left, right: right}, nil
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if _, ok := p.accept("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{operand: operand}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.advance()
+	switch t.kind {
+	case exprNumber:
+		return literalNode{value: exprValue{kind: kindNumber, num: t.value}}, nil
+	case exprString:
+		return literalNode{value: exprValue{kind: kindString, str: t.text}}, nil
+	case exprIdent:
+		return p.parseIdent(t)
+	case exprEOF:
+		return nil, p.errorf(t, "unexpected end of expression")
+	}
+
+	switch t.text {
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	case "[":
+		list := listNode{}
+		if _, ok := p.accept("]"); ok {
+			return list, nil
+		}
+		for {
+			item, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			list.items = append(list.items, item)
+			if _, ok := p.accept("]"); ok {
+				return list, nil
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, p.errorf(t, "unexpected '%s'", t.text)
+}
+
+func (p *exprParser) parseIdent(t exprToken) (exprNode, error) {
+	switch t.text {
+	case "and", "or", "not", "in":
+		return nil, p.errorf(t, "unexpected '%s'", t.text)
+	case "true", "false":
+		return literalNode{value: boolValue(t.text == "true")}, nil
+	}
+	if first, _ := utf8.DecodeRuneInString(t.text); unicode.IsUpper(first) {
+		return tagNode{tag: Tag(t.text)}, nil
+	}
+	if p.peek().text != "(" {
+		return literalNode{value: exprValue{kind: kindString, str: t.text}}, nil
+	}
+
+	fn, ok := exprFuncs[t.text]
+	if !ok {
+		return nil, p.errorf(t, "unknown function '%s', available: abs, min, max, lower, upper", t.text)
+	}
+	p.advance()
+	call := callNode{fn: fn.fn}
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if _, ok := p.accept(")"); ok {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(call.args) < fn.minArgs || len(call.args) > fn.maxArgs {
+		return nil, p.errorf(t, "wrong number of arguments for '%s': %d", t.text, len(call.args))
+	}
+
+	return call, nil
+}
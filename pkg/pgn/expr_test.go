@@ -0,0 +1,158 @@
+package pgn
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testPGN(tags ...string) *PGN {
+	pgn := &PGN{}
+	for i := 0; i+1 < len(tags); i += 2 {
+		pgn.AddTag(Tag(tags[i]), tags[i+1])
+	}
+
+	return pgn
+}
+
+func TestExprMatch(t *testing.T) {
+	game := testPGN(
+		"Event", "Titled Arena R.12",
+		"Site", "https://lichess.org/abc123",
+		"WhiteElo", "2450",
+		"BlackElo", "?",
+		"TimeControl", "600+5",
+		"Variant", "Chess 960",
+		"Result", "1-0",
+	)
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// Precedence
+		{"1 + 2 * 3 == 7", true},
+		{"(1 + 2) * 3 == 9", true},
+		{"10 - 4 - 3 == 3", true},
+		{"-2 * 3 == -6", true},
+		{"false && false || true", true},
+		{"false && (false || true)", false},
+		{"not false and false", false},
+		{"!(false and false)", true},
+		{"WhiteElo >= 2400 && WhiteElo < 2500 || false", true},
+		// Numbers and strings
+		{"WhiteElo == 2450", true},
+		{"WhiteElo == '2450'", true},
+		{"WhiteElo / 2 == 1225", true},
+		{"Result == '1-0'", true},
+		{"Result != \"0-1\"", true},
+		{"Event > 'Titled'", true},
+		{"BlackElo >= 2400", false},
+		{"BlackElo < 2400", false},
+		{"BlackElo == '?'", true},
+		// Missing tags are empty
+		{"Round == ''", true},
+		{"Round", false},
+		{"Round > 0", false},
+		{"!WhiteTitle", true},
+		{"abs(Round - 1) == 1", false},
+		// In
+		{"Result in ['1-0', '0-1']", true},
+		{"Result in ['1/2-1/2']", false},
+		{"WhiteElo in [2400, 2450]", true},
+		{"Result in []", false},
+		// Aliases
+		{"TimeControl == rapid", true},
+		{"TimeControl in [blitz, rapid]", true},
+		{"TimeControl == '600+5'", true},
+		{"Variant == chess960", true},
+		// Regular expressions
+		{"Event =~ 'Arena'", true},
+		{"Event =~ '(?i)arena'", true},
+		{`Event =~ "R\.\d+$"`, true},
+		{`Site =~ "\d+"`, true},
+		{`Event =~ "\bRena"`, false},
+		{`Event =~ "it\"s"`, false},
+		{"Event !~ 'Bullet'", true},
+		{"TimeControl =~ '^rapid$'", true},
+		// Functions
+		{"abs(2300 - WhiteElo) == 150", true},
+		{"min(WhiteElo, 2500, 2460) == 2450", true},
+		{"max(1, 3, 2) == 3", true},
+		{"lower(Event) =~ 'titled'", true},
+		{"upper(Result) == '1-0'", true},
+	}
+
+	for _, tt := range tests {
+		expr, err := CompileExpr(tt.expr)
+		if err != nil {
+			t.Errorf("CompileExpr(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := expr.Match(game); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestExprError(t *testing.T) {
+	tests := []struct {
+		expr string
+		msg  string
+		pos  int
+	}{
+		{"WhiteElo = 2400", "unexpected '=', use '==' to compare", 9},
+		{"Event == 'open", "unterminated string", 9},
+		{"1 < WhiteElo < 2400", "comparisons cannot be chained", 13},
+		{"WhiteElo >= 2400 && foo(WhiteElo)", "unknown function 'foo'", 20},
+		{"abs(1, 2)", "wrong number of arguments for 'abs': 2", 0},
+		{"min(1)", "wrong number of arguments for 'min': 1", 0},
+		{"Event =~ '('", "invalid regular expression", 9},
+		{"Event =~ Open", "'=~' expects a quoted regular expression", 9},
+		{"Result in '1-0'", "'in' expects a list", 10},
+		{"(WhiteElo > 2400", "expected ')' but found 'end of expression'", 16},
+		{"WhiteElo >", "unexpected end of expression", 10},
+		{"WhiteElo 2400", "unexpected '2400'", 9},
+		{"1.2.3", "invalid number '1.2.3'", 0},
+		{"Event == a @", "unexpected character '@'", 11},
+		{"Événement == é @", "unexpected character '@'", 18},
+	}
+
+	for _, tt := range tests {
+		_, err := CompileExpr(tt.expr)
+		var exprErr *ExprError
+		if !errors.As(err, &exprErr) {
+			t.Errorf("CompileExpr(%q) error = %v, want an ExprError", tt.expr, err)
+			continue
+		}
+		if !strings.Contains(exprErr.Msg, tt.msg) || exprErr.Pos != tt.pos {
+			t.Errorf("CompileExpr(%q) = %q at %d, want %q at %d", tt.expr, exprErr.Msg, exprErr.Pos, tt.msg, tt.pos)
+		}
+	}
+}
+
+func TestExprErrorCaret(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"WhiteElo = 2400", "filter expression: unexpected '=', use '==' to compare at column 10\n\tWhiteElo = 2400\n\t         ^"},
+		{"a é ?", "filter expression: unexpected character '?' at column 5\n\ta é ?\n\t    ^"},
+	}
+
+	for _, tt := range tests {
+		_, err := CompileExpr(tt.expr)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("CompileExpr(%q) error =\n%v\nwant\n%s", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestExprNonASCII(t *testing.T) {
+	expr, err := CompileExpr("White == Événement || Site == é")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !expr.Match(testPGN("Site", "é")) {
+		t.Errorf("%s did not match", expr)
+	}
+}
@@ -0,0 +1,66 @@
+package pgn
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Check a FEN before handing it to board.NewBoard: piece placement, side to move, castling rights in X-FEN (KQkq) or
+// Shredder-FEN (HAha) notation, en passant square and the optional move counters.
+func ValidateFEN(fen string) error {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 || len(fields) > 6 {
+		return fmt.Errorf("invalid fen: '%s', expected 4 to 6 fields", fen)
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return fmt.Errorf("invalid fen: '%s', expected 8 ranks", fen)
+	}
+	kings := map[rune]int{}
+	for i, rank := range ranks {
+		files := 0
+		for _, ch := range rank {
+			switch {
+			case ch >= '1' && ch <= '8':
+				files += int(ch - '0')
+			case strings.ContainsRune("pnbrqkPNBRQK", ch):
+				if (ch == 'p' || ch == 'P') && (i == 0 || i == 7) {
+					return fmt.Errorf("invalid fen: '%s', pawn on the back rank", fen)
+				}
+				kings[ch]++
+				files++
+			default:
+				return fmt.Errorf("invalid fen: '%s', unexpected piece '%c'", fen, ch)
+			}
+		}
+		if files != 8 {
+			return fmt.Errorf("invalid fen: '%s', rank %d has %d files", fen, 8-i, files)
+		}
+	}
+	if kings['K'] != 1 || kings['k'] != 1 {
+		return fmt.Errorf("invalid fen: '%s', expected one king per side", fen)
+	}
+
+	if fields[1] != "w" && fields[1] != "b" {
+		return fmt.Errorf("invalid fen: '%s', side to move must be w or b", fen)
+	}
+	if fields[2] != "-" {
+		for _, ch := range fields[2] {
+			if !strings.ContainsRune("KQkqABCDEFGHabcdefgh", ch) || strings.Count(fields[2], string(ch)) > 1 {
+				return fmt.Errorf("invalid fen: '%s', invalid castling rights", fen)
+			}
+		}
+	}
+	if ep := fields[3]; ep != "-" && (len(ep) != 2 || ep[0] < 'a' || ep[0] > 'h' || (ep[1] != '3' && ep[1] != '6')) {
+		return fmt.Errorf("invalid fen: '%s', invalid en passant square", fen)
+	}
+	for _, counter := range fields[4:] {
+		if n, err := strconv.Atoi(counter); err != nil || n < 0 {
+			return fmt.Errorf("invalid fen: '%s', invalid move counter", fen)
+		}
+	}
+
+	return nil
+}
@@ -1,52 +1,459 @@
 package pgn
 
 import (
-	"strconv"
+	"log"
 	"strings"
+	"time"
+
+	"github.com/likeawizard/tofiks/pkg/board"
 )
 
-// Filter conditions that can be determined on individual Tags.
-func PreFilter(tag Tag, value string) bool {
+// Variants of the games to keep, comma separated. Games of other variants are skipped regardless of filtering.
+var Variant = VARIANT_STANDARD
+
+// Skip games starting from a custom position (SetUp/FEN tags) regardless of filtering.
+var SkipSetUp bool
+
+// Selects games while they are parsed. A game is selected if all of its tags and the complete game match.
+type Filter interface {
+	// Called for every tag pair as the tag section is read, a game is skipped as soon as a tag does not match.
+	// Games missing a tag are not filtered on it.
+	MatchTag(tag Tag, value string) bool
+	// Called once the game is read completely.
+	MatchGame(pgn *PGN) bool
+}
+
+// Adapter to use functions as a Filter. Nil functions match everything.
+type FilterFuncs struct {
+	Tag  func(tag Tag, value string) bool
+	Game func(pgn *PGN) bool
+	// Whether the filter applies to the game, ie the game has the tags it filters on. Not and Or leave games the filter does not
+	// apply to alone. Nil applies to all games.
+	Applies func(pgn *PGN) bool
+}
+
+func (f FilterFuncs) MatchTag(tag Tag, value string) bool {
+	return f.Tag == nil || f.Tag(tag, value)
+}
+
+func (f FilterFuncs) MatchGame(pgn *PGN) bool {
+	return f.Game == nil || f.Game(pgn)
+}
+
+func (f FilterFuncs) appliesTo(pgn *PGN) bool {
+	return f.Applies == nil || f.Applies(pgn)
+}
+
+// Filters that can tell the games they apply to. Other filters apply to all games.
+type applicable interface {
+	appliesTo(pgn *PGN) bool
+}
+
+func applies(f Filter, pgn *PGN) bool {
+	a, ok := f.(applicable)
+	return !ok || a.appliesTo(pgn)
+}
+
+// Applies to games having any of the tags.
+func hasAnyTag(tags ...Tag) func(pgn *PGN) bool {
+	return func(pgn *PGN) bool {
+		for _, tag := range tags {
+			if _, ok := pgn.LookupTag(tag); ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Applies to games with both ratings.
+func hasRatings(pgn *PGN) bool {
+	_, ok := pgn.WhiteElo()
+	_, ok2 := pgn.BlackElo()
+	return ok && ok2
+}
+
+// Whether the game matches the filter on all of its tags and as a whole.
+func Matches(f Filter, pgn *PGN) bool {
+	for _, pair := range pgn.Tags {
+		if !f.MatchTag(pair.Tag, pair.Value) {
+			return false
+		}
+	}
+
+	return f.MatchGame(pgn)
+}
+
+type andFilter []Filter
+
+// Games matching all of the filters.
+func And(filters ...Filter) Filter {
+	return andFilter(filters)
+}
+
+func (f andFilter) MatchTag(tag Tag, value string) bool {
+	for _, filter := range f {
+		if !filter.MatchTag(tag, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f andFilter) MatchGame(pgn *PGN) bool {
+	for _, filter := range f {
+		if !filter.MatchGame(pgn) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f andFilter) appliesTo(pgn *PGN) bool {
+	for _, filter := range f {
+		if applies(filter, pgn) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// A game can fail one filter on one tag and another on a different tag, so Or and Not are decided on the complete game.
+// Like games missing a tag are not filtered on it, both only decide on the filters that apply to the game: Not(TimeControls(...))
+// keeps games without a TimeControl tag and Or keeps games none of its filters apply to.
+type orFilter []Filter
+
+// Games matching any of the filters.
+func Or(filters ...Filter) Filter {
+	return orFilter(filters)
+}
+
+func (f orFilter) MatchTag(_ Tag, _ string) bool {
+	return true
+}
+
+func (f orFilter) MatchGame(pgn *PGN) bool {
+	applied := false
+	for _, filter := range f {
+		if !applies(filter, pgn) {
+			continue
+		}
+		if Matches(filter, pgn) {
+			return true
+		}
+		applied = true
+	}
+
+	return !applied
+}
+
+func (f orFilter) appliesTo(pgn *PGN) bool {
+	return andFilter(f).appliesTo(pgn)
+}
+
+type notFilter struct {
+	filter Filter
+}
+
+// Games not matching the filter.
+func Not(filter Filter) Filter {
+	return notFilter{filter: filter}
+}
+
+func (f notFilter) MatchTag(_ Tag, _ string) bool {
+	return true
+}
+
+func (f notFilter) MatchGame(pgn *PGN) bool {
+	return !applies(f.filter, pgn) || !Matches(f.filter, pgn)
+}
+
+func (f notFilter) appliesTo(pgn *PGN) bool {
+	return applies(f.filter, pgn)
+}
+
+// Both players rated within the range, zero bounds are open.
+func EloRange(minElo, maxElo int) Filter {
+	return FilterFuncs{Tag: func(tag Tag, value string) bool {
+		if tag != TAG_WHITE_ELO && tag != TAG_BLACK_ELO {
+			return true
+		}
+		return matchElo(value, minElo, maxElo)
+	}, Applies: hasAnyTag(TAG_WHITE_ELO, TAG_BLACK_ELO)}
+}
+
+// Rating difference of the players at most maxDiff. Games without both ratings are not filtered.
+func EloDifference(maxDiff int) Filter {
+	return FilterFuncs{Game: func(pgn *PGN) bool {
+		white, ok := pgn.WhiteElo()
+		black, ok2 := pgn.BlackElo()
+		return !ok || !ok2 || abs(white-black) <= maxDiff
+	}, Applies: hasRatings}
+}
+
+// Time control classes ie TC_CLASS_BLITZ.
+func TimeControls(classes ...string) Filter {
+	return FilterFuncs{Tag: func(tag Tag, value string) bool {
+		return tag != TAG_TIMECONTROL || matchAny(classes, TimeControlClass(value))
+	}, Applies: hasAnyTag(TAG_TIMECONTROL)}
+}
+
+func Results(results ...string) Filter {
+	return FilterFuncs{Tag: func(tag Tag, value string) bool {
+		return tag != TAG_RESULT || matchAny(results, value)
+	}, Applies: hasAnyTag(TAG_RESULT)}
+}
+
+func Terminations(terminations ...string) Filter {
+	return FilterFuncs{Tag: func(tag Tag, value string) bool {
+		return tag != TAG_TERMINATION || matchAny(terminations, value)
+	}, Applies: hasAnyTag(TAG_TERMINATION)}
+}
+
+// Games played by any of the player's names, see PGN.PlayerColor.
+func Player(names ...string) Filter {
+	return FilterFuncs{Game: func(pgn *PGN) bool {
+		_, ok := pgn.PlayerColor(names...)
+		return ok
+	}}
+}
+
+// Games played within the date range of the Date or UTCDate tag, both ends inclusive. Zero bounds are open.
+func DateRange(since, until time.Time) Filter {
+	return FilterFuncs{Tag: func(tag Tag, value string) bool {
+		return (tag != TAG_DATE && tag != TAG_UTCDATE) || matchDate(value, since, until)
+	}, Applies: hasAnyTag(TAG_DATE, TAG_UTCDATE)}
+}
+
+// Average rating of the players at least minElo. Games without both ratings are not filtered.
+func MinAverageElo(minElo int) Filter {
+	return FilterFuncs{Game: func(pgn *PGN) bool {
+		white, ok := pgn.WhiteElo()
+		black, ok2 := pgn.BlackElo()
+		return !ok || !ok2 || (white+black)/2 >= minElo
+	}, Applies: hasRatings}
+}
+
+// Number of plies of the main line within the range, zero bounds are open.
+func PlyRange(minPlies, maxPlies int) Filter {
+	return FilterFuncs{Game: func(pgn *PGN) bool {
+		plies := len(pgn.MainLine().SANs)
+		return plies >= minPlies && (maxPlies == 0 || plies <= maxPlies)
+	}}
+}
+
+// Games whose main line reaches the position, compared on piece placement, side to move and castling rights.
+// Fails on an invalid FEN, which would match no games.
+func ReachedPosition(fen string) (Filter, error) {
+	if err := ValidateFEN(fen); err != nil {
+		return nil, err
+	}
+	key := positionKey(board.NewBoard(fen).ExportFEN())
+	return FilterFuncs{Game: func(pgn *PGN) bool {
+		reached := false
+		_ = pgn.Replay(func(b *board.Board) bool {
+			reached = positionKey(b.ExportFEN()) == key
+			return !reached
+		})
+		return reached
+	}}, nil
+}
+
+// Games whose main line is made of legal moves only.
+func LegalMoves() Filter {
+	return FilterFuncs{Game: func(pgn *PGN) bool {
+		return pgn.Replay(func(_ *board.Board) bool { return true }) == nil
+	}}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
+
+// Filter conditions that can be determined on individual tags. Games missing a tag are not filtered on it.
+// Zero values and empty lists do not filter. Variants are selected by Variant for every game, as a missing tag means standard.
+type TagFilter struct {
+	// Date range of the Date or UTCDate tag, both ends inclusive
+	Since time.Time
+	Until time.Time
+	// Time control classes ie TC_CLASS_BLITZ
+	TimeControls []string
+	Terminations []string
+	Results      []string
+	// Events containing any of the values, case insensitive
+	Events      []string
+	MinWhiteElo int
+	MaxWhiteElo int
+	MinBlackElo int
+	MaxBlackElo int
+}
+
+// The former hard-coded filter: decisive and drawn games between players rated above 2500 with normal termination, blitz or slower.
+func EliteTagFilter() *TagFilter {
+	return &TagFilter{
+		TimeControls: []string{TC_CLASS_BLITZ, TC_CLASS_RAPID, TC_CLASS_CLASSICAL},
+		Terminations: []string{TERM_NORMAL},
+		Results:      []string{"1-0", "0-1", "1/2-1/2"},
+		MinWhiteElo:  2501,
+		MinBlackElo:  2501,
+	}
+}
+
+func (f *TagFilter) MatchTag(tag Tag, value string) bool {
 	switch tag {
 	case TAG_RESULT:
-		return value == "1-0" || value == "0-1" || value == "1/2-1/2"
+		return matchAny(f.Results, value)
 	case TAG_TERMINATION:
-		return value == TERM_NORMAL
+		return matchAny(f.Terminations, value)
 	case TAG_TIMECONTROL:
-		return TC_BLITZ <= adjustedTime(value)
-	case TAG_WHITE_ELO, TAG_BLACK_ELO:
-		elo, err := strconv.Atoi(value)
-		if err != nil {
-			return false
+		return matchAny(f.TimeControls, TimeControlClass(value))
+	case TAG_EVENT:
+		if len(f.Events) == 0 {
+			return true
+		}
+		for _, event := range f.Events {
+			if strings.Contains(strings.ToLower(value), strings.ToLower(event)) {
+				return true
+			}
 		}
-		return elo > 2500
+		return false
+	case TAG_WHITE_ELO:
+		return matchElo(value, f.MinWhiteElo, f.MaxWhiteElo)
+	case TAG_BLACK_ELO:
+		return matchElo(value, f.MinBlackElo, f.MaxBlackElo)
+	case TAG_DATE, TAG_UTCDATE:
+		return matchDate(value, f.Since, f.Until)
 	default:
 		return true
 	}
 }
 
-// Filter conditions that dependant on a combination of tags. ie Elo difference
-// TODO: function stub.
-func (pgn *PGN) PostFilter() bool {
+func (f *TagFilter) MatchGame(_ *PGN) bool {
 	return true
 }
 
-func adjustedTime(value string) int {
-	parts := strings.Split(value, "+")
-	partsInt := make([]int, len(parts))
-	var err error
-	for i, part := range parts {
-		partsInt[i], err = strconv.Atoi(part)
-		if err != nil {
-			return 0
+// Applies to games having any of the tags with a condition set.
+func (f *TagFilter) appliesTo(pgn *PGN) bool {
+	for _, pair := range pgn.Tags {
+		switch pair.Tag {
+		case TAG_RESULT:
+			if len(f.Results) > 0 {
+				return true
+			}
+		case TAG_TERMINATION:
+			if len(f.Terminations) > 0 {
+				return true
+			}
+		case TAG_TIMECONTROL:
+			if len(f.TimeControls) > 0 {
+				return true
+			}
+		case TAG_EVENT:
+			if len(f.Events) > 0 {
+				return true
+			}
+		case TAG_WHITE_ELO:
+			if f.MinWhiteElo != 0 || f.MaxWhiteElo != 0 {
+				return true
+			}
+		case TAG_BLACK_ELO:
+			if f.MinBlackElo != 0 || f.MaxBlackElo != 0 {
+				return true
+			}
+		case TAG_DATE, TAG_UTCDATE:
+			if !f.Since.IsZero() || !f.Until.IsZero() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchAny(allowed []string, value string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, value) {
+			return true
 		}
 	}
-	switch len(partsInt) {
-	case 1:
-		return partsInt[0]
-	case 2:
-		return partsInt[0] + 60*partsInt[1]
+
+	return false
+}
+
+// Unrated players (`?`, `-`) only pass without bounds.
+func matchElo(value string, minElo, maxElo int) bool {
+	if minElo == 0 && maxElo == 0 {
+		return true
+	}
+	elo, ok := parseElo(value)
+
+	return ok && elo >= minElo && (maxElo == 0 || elo <= maxElo)
+}
+
+// Partial dates ie `2023.??.??` are not filtered.
+func matchDate(value string, since, until time.Time) bool {
+	date, ok := parseDate(value, "")
+	if !ok {
+		return true
+	}
+
+	return (since.IsZero() || !date.Before(since)) && (until.IsZero() || !date.After(until))
+}
+
+// Class of a TimeControl tag value, empty if unknown.
+func TimeControlClass(value string) string {
+	if value == "-" {
+		return TC_CLASS_CORRESPONDENCE
+	}
+	tc, ok := ParseTimeControl(value)
+	if !ok {
+		return ""
+	}
+
+	switch seconds := tc.Seconds(); {
+	case seconds >= TC_CLASSICAL:
+		return TC_CLASS_CLASSICAL
+	case seconds >= TC_RAPID:
+		return TC_CLASS_RAPID
+	case seconds >= TC_BLITZ:
+		return TC_CLASS_BLITZ
 	default:
-		return 0
+		return TC_CLASS_BULLET
+	}
+}
+
+// Game level conditions applied to every game regardless of filtering. Games with an invalid FEN tag are skipped as
+// board.NewBoard panics on them.
+func (pgn *PGN) isSelected() bool {
+	for _, variant := range strings.Split(Variant, ",") {
+		if !pgn.IsVariant(variant) {
+			continue
+		}
+		if SkipSetUp && pgn.IsSetUp() {
+			return false
+		}
+		if fen := pgn.Tag(TAG_FEN); fen != "" {
+			if err := ValidateFEN(fen); err != nil {
+				log.Printf("skipping game: %s\n", err)
+				return false
+			}
+		}
+		return true
 	}
+
+	return false
+}
+
+func (pgn *PGN) IsVariant(variant string) bool {
+	return pgn.Variant() == NormalizeVariant(variant)
 }
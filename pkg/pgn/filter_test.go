@@ -0,0 +1,100 @@
+package pgn
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilters(t *testing.T) {
+	blitz := testPGN("WhiteElo", "2600", "BlackElo", "2450", "TimeControl", "180+2", "Result", "1-0", "Date", "2024.03.10")
+	bullet := testPGN("WhiteElo", "2300", "BlackElo", "2350", "TimeControl", "60+0", "Result", "0-1", "Termination", "Time forfeit")
+	untagged := testPGN("Event", "Casual game")
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   [3]bool
+	}{
+		{"elo range", EloRange(2400, 0), [3]bool{true, false, true}},
+		{"elo difference", EloDifference(100), [3]bool{false, true, true}},
+		{"min average elo", MinAverageElo(2500), [3]bool{true, false, true}},
+		{"time controls", TimeControls(TC_CLASS_BLITZ), [3]bool{true, false, true}},
+		{"results", Results("1-0", "1/2-1/2"), [3]bool{true, false, true}},
+		{"terminations", Terminations(TERM_NORMAL), [3]bool{true, false, true}},
+		{"date range", DateRange(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}), [3]bool{true, true, true}},
+		{"and", And(EloRange(2200, 0), TimeControls(TC_CLASS_BULLET)), [3]bool{false, true, true}},
+		{"and of nothing", And(), [3]bool{true, true, true}},
+		{"or", Or(TimeControls(TC_CLASS_BULLET), EloRange(2500, 0)), [3]bool{false, true, true}},
+		{"or of tag and game filter", Or(TimeControls(TC_CLASS_BULLET), EloDifference(100)), [3]bool{false, true, true}},
+		{"or of nothing", Or(), [3]bool{true, true, true}},
+		{"not", Not(TimeControls(TC_CLASS_BULLET)), [3]bool{true, false, true}},
+		{"not elo difference", Not(EloDifference(100)), [3]bool{true, false, true}},
+		{"not not", Not(Not(TimeControls(TC_CLASS_BULLET))), [3]bool{false, true, true}},
+		{"not and", Not(And(TimeControls(TC_CLASS_BULLET), Results("0-1"))), [3]bool{true, false, true}},
+		{"not or", Not(Or(Results("1-0"), Terminations(TERM_NORMAL))), [3]bool{false, true, true}},
+		{"not tag filter", Not(&TagFilter{Results: []string{"1-0"}}), [3]bool{false, true, true}},
+		{"not unset tag filter", Not(&TagFilter{}), [3]bool{true, true, true}},
+		{"not custom filter", Not(FilterFuncs{Tag: func(tag Tag, _ string) bool { return tag != TAG_EVENT }}), [3]bool{false, false, true}},
+	}
+
+	for _, tt := range tests {
+		for i, game := range []*PGN{blitz, bullet, untagged} {
+			if got := Matches(tt.filter, game); got != tt.want[i] {
+				t.Errorf("%s: game %d = %v, want %v", tt.name, i, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestGameFilters(t *testing.T) {
+	italian := &PGN{Moves: "1. e4 e5 2. Nf3 Nc6 3. Bc4 Bc5 1-0"}
+	transposed := &PGN{Moves: "1. Nf3 Nc6 2. e4 e5 1/2-1/2"}
+	illegal := &PGN{Moves: "1. e4 e5 2. Ke3 Nc6 0-1"}
+	empty := &PGN{Moves: "*"}
+
+	reached, err := ReachedPosition("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Move counters are optional and ignored
+	start, err := ReachedPosition("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   [4]bool
+	}{
+		{"ply range", PlyRange(4, 6), [4]bool{true, true, true, false}},
+		{"min plies", PlyRange(5, 0), [4]bool{true, false, false, false}},
+		{"max plies", PlyRange(0, 4), [4]bool{false, true, true, true}},
+		{"reached position", reached, [4]bool{true, true, false, false}},
+		{"start position", start, [4]bool{true, true, true, true}},
+		{"legal moves", LegalMoves(), [4]bool{true, true, false, true}},
+	}
+
+	for _, tt := range tests {
+		for i, game := range []*PGN{italian, transposed, illegal, empty} {
+			if got := Matches(tt.filter, game); got != tt.want[i] {
+				t.Errorf("%s: game %d = %v, want %v", tt.name, i, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestReachedPositionInvalid(t *testing.T) {
+	for _, fen := range []string{
+		"",
+		"e4",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e5 0 1",
+	} {
+		if _, err := ReachedPosition(fen); err == nil {
+			t.Errorf("ReachedPosition(%q) accepted an invalid FEN", fen)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package pgn
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// Preset chosen by the command
+	FILTER_AUTO  = "auto"
+	FILTER_ELITE = "elite"
+	FILTER_NONE  = "none"
+)
+
+// Command line flags of a TagFilter shared by the commands.
+type FilterFlags struct {
+	fs *flag.FlagSet
+	// Names of the filter flags
+	names  map[string]bool
+	preset string
+	since  string
+	until  string
+	where  string
+	values TagFilter
+	// Game filters
+	position   string
+	maxEloDiff int
+	minAvgElo  int
+	minPlies   int
+	maxPlies   int
+	legal      bool
+}
+
+func NewFilterFlags(fs *flag.FlagSet) *FilterFlags {
+	ff := &FilterFlags{fs: fs, names: map[string]bool{}}
+	existing := map[string]bool{}
+	fs.VisitAll(func(f *flag.Flag) {
+		existing[f.Name] = true
+	})
+	fs.StringVar(&ff.preset, "filter", FILTER_AUTO, "Tag filter preset the other filter flags are applied on: auto, elite (rated above 2500, blitz or slower, normal termination) or none.")
+	fs.IntVar(&ff.values.MinWhiteElo, "min-white-elo", 0, "Minimum Elo of the white player.")
+	fs.IntVar(&ff.values.MaxWhiteElo, "max-white-elo", 0, "Maximum Elo of the white player.")
+	fs.IntVar(&ff.values.MinBlackElo, "min-black-elo", 0, "Minimum Elo of the black player.")
+	fs.IntVar(&ff.values.MaxBlackElo, "max-black-elo", 0, "Maximum Elo of the black player.")
+	fs.Func("tc", "Comma separated time control classes: bullet, blitz, rapid, classical, correspondence.", listFlag(&ff.values.TimeControls))
+	fs.Func("termination", "Comma separated Termination tag values ie 'Normal,Time forfeit'.", listFlag(&ff.values.Terminations))
+	fs.Func("result", "Comma separated results ie '1-0,0-1'.", listFlag(&ff.values.Results))
+	fs.Func("event", "Comma separated strings the Event tag contains, case insensitive.", listFlag(&ff.values.Events))
+	fs.StringVar(&ff.since, "since", "", "Only games played on or after the date (YYYY-MM-DD).")
+	fs.StringVar(&ff.until, "until", "", "Only games played on or before the date (YYYY-MM-DD).")
+	fs.IntVar(&ff.maxEloDiff, "max-elo-diff", 0, "Maximum rating difference of the players.")
+	fs.IntVar(&ff.minAvgElo, "min-avg-elo", 0, "Minimum average rating of the players.")
+	fs.IntVar(&ff.minPlies, "min-plies", 0, "Minimum number of plies (half moves) of the main line.")
+	fs.IntVar(&ff.maxPlies, "max-plies", 0, "Maximum number of plies (half moves) of the main line.")
+	fs.StringVar(&ff.position, "position", "", "Only games reaching the position (FEN) in their main line.")
+	fs.BoolVar(&ff.legal, "legal", false, "Skip games with invalid or illegal moves.")
+	fs.StringVar(&ff.where, "where", "", "Filter expression on tags ie 'WhiteElo >= 2400 && abs(WhiteElo - BlackElo) < 200 && TimeControl in [rapid, classical]'.")
+
+	fs.VisitAll(func(f *flag.Flag) {
+		ff.names[f.Name] = !existing[f.Name] && f.Name != "filter"
+	})
+
+	return ff
+}
+
+func listFlag(list *[]string) func(string) error {
+	return func(value string) error {
+		*list = nil
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				*list = append(*list, v)
+			}
+		}
+		return nil
+	}
+}
+
+// Build the filter after the flags are parsed: the preset (auto resolving to autoPreset) with the filter flags that were set
+// applied on top. Nil when there is nothing to filter on.
+func (ff *FilterFlags) Filter(autoPreset string) (Filter, error) {
+	preset := ff.preset
+	if preset == FILTER_AUTO {
+		preset = autoPreset
+	}
+
+	var filter *TagFilter
+	switch preset {
+	case FILTER_ELITE:
+		filter = EliteTagFilter()
+	case FILTER_NONE:
+		filter = &TagFilter{}
+	default:
+		return nil, fmt.Errorf("unknown filter preset: %s", ff.preset)
+	}
+
+	set := map[string]bool{}
+	ff.fs.Visit(func(f *flag.Flag) {
+		if ff.names[f.Name] {
+			set[f.Name] = true
+		}
+	})
+
+	if set["min-white-elo"] {
+		filter.MinWhiteElo = ff.values.MinWhiteElo
+	}
+	if set["max-white-elo"] {
+		filter.MaxWhiteElo = ff.values.MaxWhiteElo
+	}
+	if set["min-black-elo"] {
+		filter.MinBlackElo = ff.values.MinBlackElo
+	}
+	if set["max-black-elo"] {
+		filter.MaxBlackElo = ff.values.MaxBlackElo
+	}
+	if set["tc"] {
+		filter.TimeControls = ff.values.TimeControls
+	}
+	if set["termination"] {
+		filter.Terminations = ff.values.Terminations
+	}
+	if set["result"] {
+		filter.Results = ff.values.Results
+	}
+	if set["event"] {
+		filter.Events = ff.values.Events
+	}
+	var err error
+	if set["since"] {
+		if filter.Since, err = parseFlagDate(ff.since); err != nil {
+			return nil, err
+		}
+	}
+	if set["until"] {
+		if filter.Until, err = parseFlagDate(ff.until); err != nil {
+			return nil, err
+		}
+	}
+	for _, class := range filter.TimeControls {
+		switch class {
+		case TC_CLASS_BULLET, TC_CLASS_BLITZ, TC_CLASS_RAPID, TC_CLASS_CLASSICAL, TC_CLASS_CORRESPONDENCE:
+		default:
+			return nil, fmt.Errorf("unknown time control class: %s", class)
+		}
+	}
+
+	// Cheaper filters first, as And stops at the first filter a game fails
+	filters := []Filter{filter}
+	if set["max-elo-diff"] {
+		filters = append(filters, EloDifference(ff.maxEloDiff))
+	}
+	if set["min-avg-elo"] {
+		filters = append(filters, MinAverageElo(ff.minAvgElo))
+	}
+	if set["where"] {
+		expr, err := CompileExpr(ff.where)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, expr)
+	}
+	if set["min-plies"] || set["max-plies"] {
+		filters = append(filters, PlyRange(ff.minPlies, ff.maxPlies))
+	}
+	if set["position"] {
+		reached, err := ReachedPosition(ff.position)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -position: %w", err)
+		}
+		filters = append(filters, reached)
+	}
+	if ff.legal {
+		filters = append(filters, LegalMoves())
+	}
+
+	switch {
+	case preset == FILTER_NONE && len(set) == 0:
+		return nil, nil
+	case len(filters) > 1:
+		return And(filters...), nil
+	default:
+		return filter, nil
+	}
+}
+
+// Register the flags of the Lichess options. The API token defaults to the LICHESS_TOKEN environment variable to keep it out of
+// the process list.
+func LichessFlags(fs *flag.FlagSet) {
+	fs.StringVar(&Lichess.Token, "lichess-token", os.Getenv("LICHESS_TOKEN"), "Lichess API token for a faster download rate. Defaults to $LICHESS_TOKEN.")
+	fs.Func("lichess-since", "Only lichess games played on or after the date (YYYY-MM-DD).", func(value string) error {
+		var err error
+		Lichess.Since, err = parseFlagDate(value)
+		return err
+	})
+	fs.Func("lichess-until", "Only lichess games played on or before the date (YYYY-MM-DD).", func(value string) error {
+		until, err := parseFlagDate(value)
+		Lichess.Until = until.AddDate(0, 0, 1)
+		return err
+	})
+	fs.Func("lichess-perf", "Comma separated lichess perf types ie 'blitz,rapid,chess960'.", listFlag(&Lichess.PerfTypes))
+	fs.IntVar(&Lichess.Max, "lichess-max", 0, "Maximum number of lichess games per user, all if zero.")
+}
+
+func parseFlagDate(value string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return date, fmt.Errorf("invalid date '%s', expected YYYY-MM-DD", value)
+	}
+
+	return date, nil
+}
@@ -0,0 +1,83 @@
+package pgn
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+func parseFilterFlags(t *testing.T, args ...string) (Filter, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.String("pgn", "", "")
+	ff := NewFilterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	return ff.Filter(FILTER_NONE)
+}
+
+func TestFilterFlags(t *testing.T) {
+	strong := testPGN("WhiteElo", "2600", "BlackElo", "2550", "TimeControl", "300+0")
+	weak := testPGN("WhiteElo", "1800", "BlackElo", "2550", "TimeControl", "60+0")
+	weak.Moves = "1. e4 e5 2. Nf3 *"
+
+	tests := []struct {
+		args []string
+		nil  bool
+		want [2]bool
+	}{
+		{args: []string{"-pgn", "games.pgn"}, nil: true},
+		{args: []string{"-filter", "elite"}, want: [2]bool{true, false}},
+		{args: []string{"-min-white-elo", "2000"}, want: [2]bool{true, false}},
+		{args: []string{"-tc", "bullet"}, want: [2]bool{false, true}},
+		{args: []string{"-max-elo-diff", "100"}, want: [2]bool{true, false}},
+		{args: []string{"-min-avg-elo", "2200"}, want: [2]bool{true, false}},
+		{args: []string{"-min-plies", "2", "-max-plies", "3"}, want: [2]bool{false, true}},
+		{args: []string{"-position", "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"}, want: [2]bool{false, true}},
+		{args: []string{"-where", "BlackElo > WhiteElo"}, want: [2]bool{false, true}},
+	}
+
+	for _, tt := range tests {
+		filter, err := parseFilterFlags(t, tt.args...)
+		if err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if (filter == nil) != tt.nil {
+			t.Errorf("%v: filter = %v, want nil %v", tt.args, filter, tt.nil)
+			continue
+		}
+		if filter == nil {
+			continue
+		}
+		for i, game := range []*PGN{strong, weak} {
+			if got := Matches(filter, game); got != tt.want[i] {
+				t.Errorf("%v: game %d = %v, want %v", tt.args, i, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestFilterFlagErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"-filter", "strong"}, "unknown filter preset"},
+		{[]string{"-tc", "blitz,hyper"}, "unknown time control class: hyper"},
+		{[]string{"-since", "2024/01/01"}, "invalid date"},
+		{[]string{"-position", "e4"}, "invalid -position"},
+		{[]string{"-position", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN w KQkq - 0 1"}, "invalid -position"},
+		{[]string{"-where", "WhiteElo = 2400"}, "use '=='"},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseFilterFlags(t, tt.args...); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%v: err = %v, want %q", tt.args, err, tt.err)
+		}
+	}
+}
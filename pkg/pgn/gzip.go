@@ -0,0 +1,71 @@
+package pgn
+
+import (
+	"compress/gzip"
+
+	"github.com/inhies/go-bytesize"
+)
+
+type GzipPGN struct {
+	pgn          *LineReader
+	inputReader  *ByteCountingReader
+	outputReader *ByteCountingReader
+	close        closeFn
+	path         string
+	size         bytesize.ByteSize
+}
+
+func NewGzipPGN(path string) *GzipPGN {
+	return &GzipPGN{
+		path: path,
+	}
+}
+
+func (s *GzipPGN) Open() error {
+	reader, size, close, err := openSource(s.path)
+	if err != nil {
+		return err
+	}
+	// Wrap the file and the gzip Reader in ByteCountingReader to estimate the data size by output/input ratio
+	s.inputReader = &ByteCountingReader{reader: reader}
+	gzReader, err := gzip.NewReader(s.inputReader)
+	if err != nil {
+		_ = close()
+		return err
+	}
+
+	s.outputReader = &ByteCountingReader{reader: gzReader}
+	s.close = close
+	s.size = size
+	s.pgn = NewLineReader(s.outputReader)
+
+	return nil
+}
+
+func (s *GzipPGN) Close() error {
+	return s.close()
+}
+
+func (s *GzipPGN) Scan() bool {
+	return s.pgn.Scan()
+}
+
+func (s *GzipPGN) Text() string {
+	return s.pgn.Text()
+}
+
+func (s *GzipPGN) Err() error {
+	return s.pgn.Err()
+}
+
+func (s *GzipPGN) Size() bytesize.ByteSize {
+	if s.inputReader.bytesRead > 0 {
+		return s.size * (s.outputReader.bytesRead / s.inputReader.bytesRead)
+	}
+
+	return s.size
+}
+
+func (s *GzipPGN) BytesRead() bytesize.ByteSize {
+	return s.outputReader.bytesRead
+}
@@ -0,0 +1,307 @@
+package pgn
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inhies/go-bytesize"
+)
+
+var (
+	// Number of times a dropped or failed download is resumed before giving up.
+	HTTPRetries = 5
+	// Wait before resuming a download, doubled with every retry.
+	HTTPRetryWait = time.Second
+	// Directory to cache downloads in and reuse them on later runs. Downloads are not cached if empty.
+	CacheDir string
+)
+
+// Reader over an HTTP download that resumes from the last byte offset with Range requests when the connection drops.
+// The data is optionally written to a cache file and verified against the `.sha256` published next to the url. The games
+// are streamed while downloading, so a checksum mismatch is only reported by the final Read after every game has been
+// parsed. The mismatching download is not cached.
+type httpReader struct {
+	hash hash.Hash
+	body io.ReadCloser
+	// Partial download from a previous run, read before resuming the download.
+	replay    *os.File
+	cache     *os.File
+	url       string
+	cachePath string
+	sha256    string
+	offset    int64
+	size      int64
+	retries   int
+}
+
+func openHTTP(u string) (io.Reader, bytesize.ByteSize, closeFn, error) {
+	cachePath := cachePathFor(u)
+	if cachePath != "" {
+		if file, err := os.Open(cachePath); err == nil {
+			stat, err := file.Stat()
+			if err != nil {
+				_ = file.Close()
+				return nil, 0, nil, err
+			}
+			return file, bytesize.ByteSize(stat.Size()), file.Close, nil
+		}
+	}
+
+	hr := &httpReader{
+		url:       u,
+		hash:      sha256.New(),
+		sha256:    publishedSHA256(u),
+		cachePath: cachePath,
+	}
+
+	if cachePath != "" {
+		if err := hr.openCache(); err != nil {
+			return nil, 0, nil, err
+		}
+	}
+
+	if err := hr.connect(); err != nil {
+		_ = hr.Close()
+		return nil, 0, nil, err
+	}
+
+	return hr, bytesize.ByteSize(max(hr.size, 0)), hr.Close, nil
+}
+
+// Downloads are cached under a name unique to the url, keeping the file name for readability.
+func cachePathFor(u string) string {
+	if CacheDir == "" {
+		return ""
+	}
+	name := "download"
+	if parsed, err := url.Parse(u); err == nil && path.Base(parsed.Path) != "/" && path.Base(parsed.Path) != "." {
+		name = path.Base(parsed.Path)
+	}
+	sum := sha256.Sum256([]byte(u))
+
+	return filepath.Join(CacheDir, hex.EncodeToString(sum[:4])+"-"+name)
+}
+
+// Checksums are tiny, a server not answering in time is treated as not publishing one.
+var checksumClient = &http.Client{Timeout: 30 * time.Second}
+
+// Checksum published as `<url>.sha256` in the `sha256sum` format, the query of the url is kept. Empty if there is none.
+func publishedSHA256(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	parsed.Path += ".sha256"
+	parsed.RawPath = ""
+
+	r, err := checksumClient.Get(parsed.String())
+	if err != nil {
+		return ""
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	line, _ := bufio.NewReader(io.LimitReader(r.Body, 1024)).ReadString('\n')
+	fields := strings.Fields(line)
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return ""
+	}
+
+	return strings.ToLower(fields[0])
+}
+
+// Continue a partial download of a previous run. The partial data is replayed before resuming the download.
+func (hr *httpReader) openCache() error {
+	if err := os.MkdirAll(filepath.Dir(hr.cachePath), 0o755); err != nil {
+		return err
+	}
+
+	var err error
+	hr.cache, err = os.OpenFile(hr.cachePath+".part", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	stat, err := hr.cache.Stat()
+	if err != nil {
+		return err
+	}
+
+	if stat.Size() > 0 {
+		hr.replay, err = os.Open(hr.cachePath + ".part")
+		if err != nil {
+			return err
+		}
+		hr.offset = stat.Size()
+	}
+
+	return nil
+}
+
+func (hr *httpReader) connect() error {
+	req, err := http.NewRequest(http.MethodGet, hr.url, nil)
+	if err != nil {
+		return err
+	}
+	if hr.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", hr.offset))
+	}
+
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	switch r.StatusCode {
+	case http.StatusPartialContent:
+		hr.size = contentRangeSize(r.Header.Get("Content-Range"))
+		if hr.size < 0 && r.ContentLength >= 0 {
+			hr.size = hr.offset + r.ContentLength
+		}
+	case http.StatusOK:
+		// Range is not supported, skip the data already read
+		if _, err := io.CopyN(io.Discard, r.Body, hr.offset); err != nil {
+			_ = r.Body.Close()
+			return err
+		}
+		hr.size = r.ContentLength
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial download is already complete
+		_ = r.Body.Close()
+		hr.size = hr.offset
+		hr.body = http.NoBody
+		return nil
+	default:
+		_ = r.Body.Close()
+		return fmt.Errorf("download '%s' failed: %s", hr.url, r.Status)
+	}
+	hr.body = r.Body
+
+	return nil
+}
+
+func (hr *httpReader) Read(p []byte) (int, error) {
+	if hr.replay != nil {
+		n, err := hr.replay.Read(p)
+		hr.hash.Write(p[:n])
+		if err == io.EOF {
+			_ = hr.replay.Close()
+			hr.replay = nil
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+
+	for {
+		n, err := hr.body.Read(p)
+		if n > 0 {
+			hr.offset += int64(n)
+			hr.hash.Write(p[:n])
+			if hr.cache != nil {
+				if _, err := hr.cache.Write(p[:n]); err != nil {
+					return n, err
+				}
+			}
+		}
+
+		switch {
+		case err == nil:
+			return n, nil
+		case errors.Is(err, io.EOF) && (hr.size <= 0 || hr.offset >= hr.size):
+			return n, hr.finish()
+		case hr.retries < HTTPRetries:
+			// The connection dropped, resume from the current offset
+			_ = hr.body.Close()
+			time.Sleep(HTTPRetryWait << hr.retries)
+			hr.retries++
+			if err := hr.connect(); err != nil {
+				hr.body = io.NopCloser(errReader{err})
+			}
+			if n > 0 {
+				return n, nil
+			}
+		default:
+			return n, err
+		}
+	}
+}
+
+// Verify the checksum and move the complete download into the cache. A mismatch is returned in place of io.EOF.
+func (hr *httpReader) finish() error {
+	if hr.sha256 != "" && hex.EncodeToString(hr.hash.Sum(nil)) != hr.sha256 {
+		if hr.cache != nil {
+			_ = hr.cache.Close()
+			hr.cache = nil
+			_ = os.Remove(hr.cachePath + ".part")
+		}
+		return fmt.Errorf("download '%s' does not match its sha256 checksum", hr.url)
+	}
+
+	if hr.cache != nil {
+		err := hr.cache.Close()
+		hr.cache = nil
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(hr.cachePath+".part", hr.cachePath); err != nil {
+			return err
+		}
+	}
+
+	return io.EOF
+}
+
+// Close the download, a partial download is kept in the cache to be resumed on the next run.
+func (hr *httpReader) Close() error {
+	var err error
+	if hr.body != nil {
+		err = hr.body.Close()
+	}
+	if hr.replay != nil {
+		_ = hr.replay.Close()
+	}
+	if hr.cache != nil {
+		_ = hr.cache.Close()
+	}
+
+	return err
+}
+
+// Reader failing with the error, keeps the retry loop going after a failed reconnect.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(_ []byte) (int, error) {
+	return 0, r.err
+}
+
+// Parse `bytes <start>-<end>/<size>`, returns -1 when the size is unknown.
+func contentRangeSize(value string) int64 {
+	_, size, ok := strings.Cut(value, "/")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
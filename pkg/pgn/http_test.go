@@ -0,0 +1,210 @@
+package pgn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var httpGames = []byte(strings.Repeat("[Event \"Casual\"]\n\n1. e4 e5 2. Nf3 Nc6 1-0\n\n", 100))
+
+// Serves httpGames at /games.pgn with Range support and records the requests.
+type httpServer struct {
+	*httptest.Server
+	// Published at /games.pgn.sha256 when set
+	sha256 string
+	// Drop the connection halfway through the first full download
+	drop bool
+
+	lock     sync.Mutex
+	requests []string
+}
+
+func newHTTPServer(t *testing.T, sha256 string, drop bool) *httpServer {
+	t.Helper()
+	retryWait := HTTPRetryWait
+	HTTPRetryWait = time.Millisecond
+	t.Cleanup(func() { HTTPRetryWait = retryWait })
+
+	s := &httpServer{sha256: sha256, drop: drop}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *httpServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	s.requests = append(s.requests, r.URL.RequestURI()+" "+r.Header.Get("Range"))
+	drop := s.drop && r.URL.Path == "/games.pgn" && r.Header.Get("Range") == ""
+	s.drop = s.drop && !drop
+	s.lock.Unlock()
+
+	switch r.URL.Path {
+	case "/games.pgn.sha256":
+		if s.sha256 == "" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, s.sha256+"  games.pgn\n")
+	case "/games.pgn":
+		if drop {
+			// The connection is closed before the announced length is sent
+			w.Header().Set("Content-Length", strconv.Itoa(len(httpGames)))
+			_, _ = w.Write(httpGames[:len(httpGames)/2])
+			return
+		}
+		http.ServeContent(w, r, "games.pgn", time.Time{}, bytes.NewReader(httpGames))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Requests for the games only, without checksum requests.
+func (s *httpServer) downloads() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var downloads []string
+	for _, request := range s.requests {
+		if strings.HasPrefix(request, "/games.pgn ") {
+			downloads = append(downloads, request)
+		}
+	}
+
+	return downloads
+}
+
+func readHTTP(t *testing.T, u string) ([]byte, error) {
+	t.Helper()
+	r, _, closeFn, err := openHTTP(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFn()
+
+	return io.ReadAll(r)
+}
+
+func useCacheDir(t *testing.T) {
+	t.Helper()
+	cacheDir := CacheDir
+	CacheDir = t.TempDir()
+	t.Cleanup(func() { CacheDir = cacheDir })
+}
+
+func TestHTTPRetry(t *testing.T) {
+	server := newHTTPServer(t, "", true)
+
+	data, err := readHTTP(t, server.URL+"/games.pgn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, httpGames) {
+		t.Errorf("read %d bytes, want %d", len(data), len(httpGames))
+	}
+	want := []string{"/games.pgn ", fmt.Sprintf("/games.pgn bytes=%d-", len(httpGames)/2)}
+	if downloads := server.downloads(); strings.Join(downloads, ",") != strings.Join(want, ",") {
+		t.Errorf("requests = %q, want %q", downloads, want)
+	}
+}
+
+func TestHTTPResumePartialDownload(t *testing.T) {
+	useCacheDir(t)
+	server := newHTTPServer(t, "", false)
+	u := server.URL + "/games.pgn"
+	if err := os.WriteFile(cachePathFor(u)+".part", httpGames[:1000], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := readHTTP(t, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, httpGames) {
+		t.Errorf("read %d bytes, want %d", len(data), len(httpGames))
+	}
+	want := []string{"/games.pgn bytes=1000-"}
+	if downloads := server.downloads(); strings.Join(downloads, ",") != strings.Join(want, ",") {
+		t.Errorf("requests = %q, want %q", downloads, want)
+	}
+}
+
+func TestHTTPCache(t *testing.T) {
+	useCacheDir(t)
+	server := newHTTPServer(t, "", true)
+	u := server.URL + "/games.pgn"
+
+	for i := 0; i < 2; i++ {
+		data, err := readHTTP(t, u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, httpGames) {
+			t.Errorf("read %d bytes, want %d", len(data), len(httpGames))
+		}
+	}
+	// The second read is served from the cache
+	if downloads := server.downloads(); len(downloads) != 2 {
+		t.Errorf("requests = %q, want the dropped and the resumed download", downloads)
+	}
+	if _, err := os.Stat(cachePathFor(u) + ".part"); !os.IsNotExist(err) {
+		t.Errorf("partial download kept: %v", err)
+	}
+}
+
+func TestHTTPChecksum(t *testing.T) {
+	sum := sha256.Sum256(httpGames)
+	valid := hex.EncodeToString(sum[:])
+	invalid := strings.Repeat("0", len(valid))
+	tests := []struct {
+		name   string
+		sha256 string
+		err    bool
+	}{
+		{"no checksum", "", false},
+		{"valid", strings.ToUpper(valid), false},
+		{"invalid", invalid, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCacheDir(t)
+			server := newHTTPServer(t, tt.sha256, false)
+			u := server.URL + "/games.pgn"
+
+			data, err := readHTTP(t, u)
+			if (err != nil) != tt.err {
+				t.Errorf("err = %v, want error %v", err, tt.err)
+			}
+			// Games are streamed before the checksum is verified
+			if !bytes.Equal(data, httpGames) {
+				t.Errorf("read %d bytes, want %d", len(data), len(httpGames))
+			}
+			_, statErr := os.Stat(cachePathFor(u))
+			if cached := statErr == nil; cached == tt.err {
+				t.Errorf("cached = %v, want %v", cached, !tt.err)
+			}
+		})
+	}
+}
+
+func TestPublishedSHA256KeepsQuery(t *testing.T) {
+	server := newHTTPServer(t, strings.Repeat("a", 64), false)
+
+	if sum := publishedSHA256(server.URL + "/games.pgn?token=abc"); sum != strings.Repeat("a", 64) {
+		t.Errorf("sha256 = %q, want the published checksum", sum)
+	}
+	if request := server.requests[0]; request != "/games.pgn.sha256?token=abc " {
+		t.Errorf("request = %q, want /games.pgn.sha256?token=abc", request)
+	}
+}
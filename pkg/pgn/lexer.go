@@ -0,0 +1,163 @@
+package pgn
+
+import (
+	"strconv"
+	"strings"
+)
+
+type TokenType int
+
+const (
+	TOKEN_MOVE_NUMBER TokenType = iota
+	TOKEN_SAN
+	TOKEN_NAG
+	TOKEN_COMMENT
+	TOKEN_VARIATION_START
+	TOKEN_VARIATION_END
+	TOKEN_RESULT
+)
+
+// A movetext token. Values are normalized:
+//   - move numbers without the trailing periods, ie `12` for both `12.` and `12...`
+//   - SAN without check and mate indicators, `0-0` castling as `O-O`
+//   - NAGs as the number, move marks ie `!?` are converted to their NAG equivalent
+//   - comments without the braces or the leading semicolon
+type Token struct {
+	Value string
+	Type  TokenType
+}
+
+var moveMarks = map[string]int{
+	"!":  NAG_GOOD,
+	"?":  NAG_MISTAKE,
+	"!!": NAG_BRILLIANT,
+	"??": NAG_BLUNDER,
+	"!?": NAG_INTERESTING,
+	"?!": NAG_DUBIOUS,
+}
+
+func (pgn *PGN) Tokens() []Token {
+	return Tokenize(pgn.Moves)
+}
+
+// Split PGN movetext into tokens. Handles brace comments spanning lines, rest of line `;` comments,
+// `%` escape lines, nested variations, NAGs and move marks, and move numbers in all forms ie `12.`, `12...`, `12...Nf3`.
+func Tokenize(movetext string) []Token {
+	tokens := make([]Token, 0, len(movetext)/4)
+	lineStart := true
+
+	for i := 0; i < len(movetext); {
+		ch := movetext[i]
+		switch {
+		case ch == '\n':
+			lineStart = true
+			i++
+			continue
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			i++
+			continue
+		case ch == '%' && lineStart:
+			i = skipLine(movetext, i)
+		case ch == '{':
+			end := strings.IndexByte(movetext[i+1:], '}')
+			if end < 0 {
+				end = len(movetext) - i - 1
+			}
+			tokens = append(tokens, Token{Type: TOKEN_COMMENT, Value: strings.TrimSpace(movetext[i+1 : i+1+end])})
+			i += end + 2
+		case ch == ';':
+			end := skipLine(movetext, i)
+			tokens = append(tokens, Token{Type: TOKEN_COMMENT, Value: strings.TrimSpace(movetext[i+1 : end])})
+			i = end
+		case ch == '(':
+			tokens = append(tokens, Token{Type: TOKEN_VARIATION_START, Value: "("})
+			i++
+		case ch == ')':
+			tokens = append(tokens, Token{Type: TOKEN_VARIATION_END, Value: ")"})
+			i++
+		case ch == '$':
+			end := i + 1
+			for end < len(movetext) && isDigit(movetext[end]) {
+				end++
+			}
+			if end > i+1 {
+				tokens = append(tokens, Token{Type: TOKEN_NAG, Value: movetext[i+1 : end]})
+			}
+			i = end
+		case ch == '!' || ch == '?':
+			end := i
+			for end < len(movetext) && (movetext[end] == '!' || movetext[end] == '?') {
+				end++
+			}
+			if nag, ok := moveMarks[movetext[i:end]]; ok {
+				tokens = append(tokens, Token{Type: TOKEN_NAG, Value: strconv.Itoa(nag)})
+			}
+			i = end
+		case ch == '*':
+			tokens = append(tokens, Token{Type: TOKEN_RESULT, Value: "*"})
+			i++
+		case isSymbol(ch):
+			end := i
+			for end < len(movetext) && isSymbol(movetext[end]) {
+				end++
+			}
+			tokens = appendSymbol(tokens, movetext[i:end])
+			i = end
+			// Periods following a move number
+			for i < len(movetext) && movetext[i] == '.' {
+				i++
+			}
+		default:
+			i++
+		}
+		lineStart = false
+	}
+
+	return tokens
+}
+
+// Classify a symbol as a result, move number or SAN. A move number can be directly followed by the move ie `12.e4` in which case the
+// periods end the symbol.
+func appendSymbol(tokens []Token, symbol string) []Token {
+	switch symbol {
+	case "1-0", "0-1", "1/2-1/2":
+		return append(tokens, Token{Type: TOKEN_RESULT, Value: symbol})
+	}
+
+	allDigits := true
+	for i := 0; i < len(symbol); i++ {
+		allDigits = allDigits && isDigit(symbol[i])
+	}
+	if allDigits {
+		return append(tokens, Token{Type: TOKEN_MOVE_NUMBER, Value: symbol})
+	}
+
+	// Check marks are dropped before castling written with zeros is normalized, ie `0-0+`
+	san := strings.TrimRight(symbol, "+#")
+	switch san {
+	case "0-0":
+		san = "O-O"
+	case "0-0-0":
+		san = "O-O-O"
+	}
+
+	return append(tokens, Token{Type: TOKEN_SAN, Value: san})
+}
+
+// Index of the end of the line starting at i.
+func skipLine(text string, i int) int {
+	if end := strings.IndexByte(text[i:], '\n'); end >= 0 {
+		return i + end
+	}
+
+	return len(text)
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// Characters of SAN, move numbers and results as defined by the PGN standard symbol token.
+func isSymbol(ch byte) bool {
+	return isDigit(ch) || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || strings.IndexByte("_+#=:-/", ch) >= 0
+}
@@ -0,0 +1,131 @@
+package pgn
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name     string
+		movetext string
+		tokens   []Token
+	}{
+		{
+			name:     "move numbers",
+			movetext: "1. e4 1... e5 2.Nf3 2...Nc6",
+			tokens: []Token{
+				{"1", TOKEN_MOVE_NUMBER}, {"e4", TOKEN_SAN}, {"1", TOKEN_MOVE_NUMBER}, {"e5", TOKEN_SAN},
+				{"2", TOKEN_MOVE_NUMBER}, {"Nf3", TOKEN_SAN}, {"2", TOKEN_MOVE_NUMBER}, {"Nc6", TOKEN_SAN},
+			},
+		},
+		{
+			name:     "check and mate indicators",
+			movetext: "Bxf7+ Qh5# e8=Q+",
+			tokens:   []Token{{"Bxf7", TOKEN_SAN}, {"Qh5", TOKEN_SAN}, {"e8=Q", TOKEN_SAN}},
+		},
+		{
+			name:     "zero castling",
+			movetext: "0-0 0-0-0 O-O",
+			tokens:   []Token{{"O-O", TOKEN_SAN}, {"O-O-O", TOKEN_SAN}, {"O-O", TOKEN_SAN}},
+		},
+		{
+			name:     "zero castling with check",
+			movetext: "4. 0-0+ 0-0-0# O-O+",
+			tokens:   []Token{{"4", TOKEN_MOVE_NUMBER}, {"O-O", TOKEN_SAN}, {"O-O-O", TOKEN_SAN}, {"O-O", TOKEN_SAN}},
+		},
+		{
+			name:     "brace comments",
+			movetext: "e4 { best by\ntest } e5 {}",
+			tokens:   []Token{{"e4", TOKEN_SAN}, {"best by\ntest", TOKEN_COMMENT}, {"e5", TOKEN_SAN}, {"", TOKEN_COMMENT}},
+		},
+		{
+			name:     "unterminated comment",
+			movetext: "e4 { never closed",
+			tokens:   []Token{{"e4", TOKEN_SAN}, {"never closed", TOKEN_COMMENT}},
+		},
+		{
+			name:     "semicolon comments",
+			movetext: "e4 ; rest of line (e5)\ne5",
+			tokens:   []Token{{"e4", TOKEN_SAN}, {"rest of line (e5)", TOKEN_COMMENT}, {"e5", TOKEN_SAN}},
+		},
+		{
+			name:     "escape lines",
+			movetext: "% skipped e4\ne4 % not an escape",
+			tokens:   []Token{{"e4", TOKEN_SAN}, {"not", TOKEN_SAN}, {"an", TOKEN_SAN}, {"escape", TOKEN_SAN}},
+		},
+		{
+			name:     "nags and move marks",
+			movetext: "e4 $1 e5?! Nf3!! $ Nc6!?? d4 $146",
+			tokens: []Token{
+				{"e4", TOKEN_SAN}, {"1", TOKEN_NAG}, {"e5", TOKEN_SAN}, {"6", TOKEN_NAG}, {"Nf3", TOKEN_SAN}, {"3", TOKEN_NAG},
+				{"Nc6", TOKEN_SAN}, {"d4", TOKEN_SAN}, {"146", TOKEN_NAG},
+			},
+		},
+		{
+			name:     "nested variations",
+			movetext: "e4 (d4 (c4) Nf6) e5",
+			tokens: []Token{
+				{"e4", TOKEN_SAN}, {"(", TOKEN_VARIATION_START}, {"d4", TOKEN_SAN}, {"(", TOKEN_VARIATION_START}, {"c4", TOKEN_SAN},
+				{")", TOKEN_VARIATION_END}, {"Nf6", TOKEN_SAN}, {")", TOKEN_VARIATION_END}, {"e5", TOKEN_SAN},
+			},
+		},
+		{
+			name:     "results",
+			movetext: "e4 1-0 0-1 1/2-1/2 *",
+			tokens:   []Token{{"e4", TOKEN_SAN}, {"1-0", TOKEN_RESULT}, {"0-1", TOKEN_RESULT}, {"1/2-1/2", TOKEN_RESULT}, {"*", TOKEN_RESULT}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tokens := Tokenize(tt.movetext); !slices.Equal(tokens, tt.tokens) {
+				t.Errorf("tokens = %v, want %v", tokens, tt.tokens)
+			}
+		})
+	}
+}
+
+func TestRemoveAnnotations(t *testing.T) {
+	tests := []struct {
+		moves string
+		want  string
+	}{
+		{"1. e4 {best} e5! (1... c5 2. Nf3 (2. c3)) 2. Nf3+ $1 2... Nc6 1-0", "1. e4 e5 2. Nf3 Nc6 1-0"},
+		{"1. e4 e5 2. 0-0 ; castles\n*", "1. e4 e5 2. O-O *"},
+		{"3. Bc4 Bc5 4. 0-0+ *", "3. Bc4 Bc5 4. O-O *"},
+		{"1. d4 {only a comment} 1... d5 1/2-1/2", "1. d4 d5 1/2-1/2"},
+	}
+
+	for _, tt := range tests {
+		game := &PGN{Moves: tt.moves}
+		if got := game.RemoveAnnotations(); got != tt.want {
+			t.Errorf("RemoveAnnotations(%q) = %q, want %q", tt.moves, got, tt.want)
+		}
+	}
+}
+
+func TestGetLines(t *testing.T) {
+	game := &PGN{Moves: "1. e4 e5 (1... c5 2. Nf3 (2. c3 d5) d6) 2. Nf3 {main} 1-0"}
+	lines := game.GetLines()
+	want := []struct {
+		sans  []string
+		start int
+	}{
+		{[]string{"e4", "e5", "Nf3"}, 0},
+		{[]string{"e4", "c5", "c3", "d5"}, 2},
+		{[]string{"e4", "c5", "Nf3", "d6"}, 1},
+	}
+
+	if len(lines) != len(want) {
+		t.Fatalf("lines = %+v, want %d lines", lines, len(want))
+	}
+	for i, line := range lines {
+		if !slices.Equal(line.SANs, want[i].sans) || line.Start != want[i].start || line.Variation != (i > 0) {
+			t.Errorf("line %d = %+v, want %v from %d", i, line, want[i].sans, want[i].start)
+		}
+	}
+	if comment := lines[0].Comment(2); comment != "main" {
+		t.Errorf("comment = %q, want main", comment)
+	}
+}
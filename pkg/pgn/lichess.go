@@ -0,0 +1,150 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inhies/go-bytesize"
+)
+
+const LICHESS_URL = "https://lichess.org"
+
+type LichessOptions struct {
+	// Defaults to LICHESS_URL
+	BaseURL string
+	// Personal API token, allows a faster download rate
+	Token string
+	// Only games played in the time range, ignored if zero
+	Since time.Time
+	Until time.Time
+	// Only games of the perf types ie bullet, blitz, rapid, classical, correspondence, chess960
+	PerfTypes []string
+	// Maximum number of games, all games if zero
+	Max int
+	// Wait after a rate limited (429) response, doubled with every retry. Defaults to a minute as requested by the lichess API
+	RetryWait  time.Duration
+	MaxRetries int
+}
+
+// Options of `lichess:<user>` inputs, set by the command line flags. See LichessFlags.
+var Lichess LichessOptions
+
+// Streams a user's games from the lichess game export API.
+type LichessPGN struct {
+	pgn    *LineReader
+	reader *ByteCountingReader
+	close  closeFn
+	opts   LichessOptions
+	user   string
+	size   bytesize.ByteSize
+}
+
+func NewLichessPGN(user string, opts LichessOptions) *LichessPGN {
+	if opts.BaseURL == "" {
+		opts.BaseURL = LICHESS_URL
+	}
+	if opts.RetryWait == 0 {
+		opts.RetryWait = time.Minute
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 3
+	}
+
+	return &LichessPGN{
+		user: user,
+		opts: opts,
+	}
+}
+
+func (s *LichessPGN) Open() error {
+	req, err := http.NewRequest(http.MethodGet, s.exportURL(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/x-chess-pgn")
+	if s.opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.opts.Token)
+	}
+
+	wait := s.opts.RetryWait
+	for retry := 0; ; retry++ {
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case r.StatusCode == http.StatusOK:
+			s.reader = &ByteCountingReader{reader: r.Body}
+			s.close = r.Body.Close
+			s.size = bytesize.ByteSize(max(r.ContentLength, 0))
+			s.pgn = NewLineReader(s.reader)
+			return nil
+		case r.StatusCode == http.StatusTooManyRequests && retry < s.opts.MaxRetries:
+			_, _ = io.Copy(io.Discard, r.Body)
+			_ = r.Body.Close()
+			if seconds, err := strconv.Atoi(r.Header.Get("Retry-After")); err == nil {
+				time.Sleep(time.Duration(seconds) * time.Second)
+			} else {
+				time.Sleep(wait)
+			}
+			wait *= 2
+		default:
+			_ = r.Body.Close()
+			return fmt.Errorf("lichess export for '%s' failed: %s", s.user, r.Status)
+		}
+	}
+}
+
+func (s *LichessPGN) exportURL() string {
+	query := url.Values{}
+	if !s.opts.Since.IsZero() {
+		query.Set("since", strconv.FormatInt(s.opts.Since.UnixMilli(), 10))
+	}
+	if !s.opts.Until.IsZero() {
+		query.Set("until", strconv.FormatInt(s.opts.Until.UnixMilli(), 10))
+	}
+	if len(s.opts.PerfTypes) > 0 {
+		query.Set("perfType", strings.Join(s.opts.PerfTypes, ","))
+	}
+	if s.opts.Max > 0 {
+		query.Set("max", strconv.Itoa(s.opts.Max))
+	}
+
+	u := fmt.Sprintf("%s/api/games/user/%s", strings.TrimSuffix(s.opts.BaseURL, "/"), url.PathEscape(s.user))
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	return u
+}
+
+func (s *LichessPGN) Close() error {
+	return s.close()
+}
+
+func (s *LichessPGN) Scan() bool {
+	return s.pgn.Scan()
+}
+
+func (s *LichessPGN) Text() string {
+	return s.pgn.Text()
+}
+
+func (s *LichessPGN) Err() error {
+	return s.pgn.Err()
+}
+
+// The export is usually streamed without a known length, in which case the size is zero.
+func (s *LichessPGN) Size() bytesize.ByteSize {
+	return s.size
+}
+
+func (s *LichessPGN) BytesRead() bytesize.ByteSize {
+	return s.reader.bytesRead
+}
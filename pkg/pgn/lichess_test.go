@@ -0,0 +1,136 @@
+package pgn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inhies/go-bytesize"
+)
+
+const testGame = "[Event \"Rated blitz game\"]\n[Result \"1-0\"]\n\n1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0\n"
+
+func readLines(t *testing.T, s Source) []string {
+	t.Helper()
+	var lines []string
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return lines
+}
+
+func TestLichessOptions(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		checks := map[string]string{
+			"path":          r.URL.Path,
+			"since":         query.Get("since"),
+			"until":         query.Get("until"),
+			"perfType":      query.Get("perfType"),
+			"max":           query.Get("max"),
+			"Accept":        r.Header.Get("Accept"),
+			"Authorization": r.Header.Get("Authorization"),
+		}
+		want := map[string]string{
+			"path":          "/api/games/user/some user",
+			"since":         "1704067200000",
+			"until":         "1706745600000",
+			"perfType":      "blitz,rapid",
+			"max":           "10",
+			"Accept":        "application/x-chess-pgn",
+			"Authorization": "Bearer secret",
+		}
+		for key, value := range want {
+			if checks[key] != value {
+				t.Errorf("%s = '%s', want '%s'", key, checks[key], value)
+			}
+		}
+		_, _ = w.Write([]byte(testGame))
+	}))
+	defer server.Close()
+
+	s := NewLichessPGN("some user", LichessOptions{
+		BaseURL:   server.URL + "/",
+		Token:     "secret",
+		Since:     since,
+		Until:     until,
+		PerfTypes: []string{"blitz", "rapid"},
+		Max:       10,
+	})
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	lines := readLines(t, s)
+	if got := strings.Join(lines, "\n") + "\n"; got != testGame {
+		t.Errorf("pgn = %q, want %q", got, testGame)
+	}
+	if s.BytesRead() != bytesize.ByteSize(len(testGame)) {
+		t.Errorf("bytes read = %v, want %d", s.BytesRead(), len(testGame))
+	}
+}
+
+func TestLichessRateLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		limited    int
+		maxRetries int
+		wantErr    bool
+	}{
+		{name: "retried until served", limited: 2, maxRetries: 3},
+		{name: "retries exhausted", limited: 3, maxRetries: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tt.limited {
+					// Alternate between an explicit and the default wait
+					if requests%2 == 1 {
+						w.Header().Set("Retry-After", "0")
+					}
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				_, _ = w.Write([]byte(testGame))
+			}))
+			defer server.Close()
+
+			s := NewLichessPGN("user", LichessOptions{BaseURL: server.URL, RetryWait: time.Millisecond, MaxRetries: tt.maxRetries})
+			err := s.Open()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil {
+				defer s.Close()
+				if lines := readLines(t, s); len(lines) != 4 {
+					t.Errorf("lines = %q", lines)
+				}
+			}
+			if want := min(tt.limited, tt.maxRetries) + 1; requests != want {
+				t.Errorf("requests = %d, want %d", requests, want)
+			}
+		})
+	}
+}
+
+func TestLichessError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	s := NewLichessPGN("nobody", LichessOptions{BaseURL: server.URL})
+	if err := s.Open(); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("err = %v, want a 404 error", err)
+	}
+}
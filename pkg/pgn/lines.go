@@ -0,0 +1,71 @@
+package pgn
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+var utf8BOM = []byte("\ufeff")
+
+// Line reader replacing bufio.Scanner without its line length limit, so a whole game on a single line can be read.
+// Strips LF and CRLF line endings and a leading UTF-8 BOM. Unlike reaching the end of input, read errors are kept and returned by Err.
+type LineReader struct {
+	err    error
+	reader *bufio.Reader
+	line   []byte
+	// Bytes read including line endings
+	consumed int64
+	started  bool
+}
+
+func NewLineReader(r io.Reader) *LineReader {
+	return &LineReader{reader: bufio.NewReaderSize(r, 64*1024)}
+}
+
+func (lr *LineReader) Scan() bool {
+	if lr.err != nil {
+		return false
+	}
+
+	lr.line = lr.line[:0]
+	for {
+		chunk, err := lr.reader.ReadSlice('\n')
+		lr.line = append(lr.line, chunk...)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil {
+			lr.err = err
+			if len(lr.line) == 0 {
+				return false
+			}
+		}
+		break
+	}
+	lr.consumed += int64(len(lr.line))
+
+	if !lr.started {
+		lr.started = true
+		lr.line = bytes.TrimPrefix(lr.line, utf8BOM)
+	}
+	lr.line = bytes.TrimSuffix(lr.line, []byte("\n"))
+	lr.line = bytes.TrimSuffix(lr.line, []byte("\r"))
+
+	return true
+}
+
+// The last line read without its line ending, transcoded to UTF-8 from Charset.
+func (lr *LineReader) Text() string {
+	return toUTF8(lr.line)
+}
+
+// The first error other than io.EOF.
+func (lr *LineReader) Err() error {
+	if errors.Is(lr.err, io.EOF) {
+		return nil
+	}
+
+	return lr.err
+}
@@ -0,0 +1,319 @@
+package pgn
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Number of goroutines decompressing multi-frame zstd and multi-stream bzip2 sources. Sources are decompressed sequentially when 1.
+var DecodeWorkers = 1
+
+// Chunks are held in memory while decoding. Files made of a single large frame or stream are decoded sequentially instead.
+const maxChunkSize = 64 << 20
+
+var errChunkTooLarge = errors.New("chunk too large")
+
+// Splits compressed input into chunks that can be decoded independently.
+type chunkSplitter interface {
+	// The next chunk or io.EOF at the end of input
+	next() ([]byte, error)
+	// The remaining input including a partially read chunk after errChunkTooLarge
+	remaining() io.Reader
+}
+
+type chunkResult struct {
+	err  error
+	data []byte
+}
+
+// Reader decoding chunks of compressed data on multiple goroutines and serving the output in input order.
+type parallelReader struct {
+	err      error
+	reader   io.Reader
+	splitter chunkSplitter
+	fallback func(io.Reader) (io.Reader, error)
+	results  chan chan chunkResult
+	done     chan struct{}
+	current  []byte
+	once     sync.Once
+}
+
+// The fallback decodes the remaining input sequentially when a chunk is too large.
+func newParallelReader(splitter chunkSplitter, decode func([]byte) ([]byte, error), fallback func(io.Reader) (io.Reader, error), workers int) *parallelReader {
+	pr := &parallelReader{
+		splitter: splitter,
+		fallback: fallback,
+		results:  make(chan chan chunkResult, workers),
+		done:     make(chan struct{}),
+	}
+	go pr.produce(decode, workers)
+
+	return pr
+}
+
+func (pr *parallelReader) produce(decode func([]byte) ([]byte, error), workers int) {
+	defer close(pr.results)
+	workerSlots := make(chan struct{}, workers)
+	for {
+		result := make(chan chunkResult, 1)
+		chunk, err := pr.splitter.next()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+
+		if err != nil {
+			result <- chunkResult{err: err}
+		} else {
+			select {
+			case workerSlots <- struct{}{}:
+			case <-pr.done:
+				return
+			}
+			go func() {
+				data, err := decode(chunk)
+				result <- chunkResult{data: data, err: err}
+				<-workerSlots
+			}()
+		}
+
+		select {
+		case pr.results <- result:
+		case <-pr.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (pr *parallelReader) Read(p []byte) (int, error) {
+	for len(pr.current) == 0 {
+		if pr.reader != nil {
+			return pr.reader.Read(p)
+		}
+		if pr.err != nil {
+			return 0, pr.err
+		}
+
+		result, ok := <-pr.results
+		if !ok {
+			pr.err = io.EOF
+			continue
+		}
+		r := <-result
+		switch {
+		case errors.Is(r.err, errChunkTooLarge):
+			pr.reader, pr.err = pr.fallback(pr.splitter.remaining())
+		case r.err != nil:
+			pr.err = r.err
+		default:
+			pr.current = r.data
+		}
+	}
+
+	n := copy(p, pr.current)
+	pr.current = pr.current[n:]
+
+	return n, nil
+}
+
+// Stop decoding ahead.
+func (pr *parallelReader) Close() {
+	pr.once.Do(func() {
+		close(pr.done)
+	})
+}
+
+const (
+	zstdMagic          = 0xfd2fb528
+	zstdSkippableMagic = 0x184d2a50
+)
+
+// Splits a zstd stream into its frames by walking the frame and block headers without decoding.
+type zstdFrames struct {
+	r     *bufio.Reader
+	frame []byte
+}
+
+func newZstdFrames(r io.Reader) *zstdFrames {
+	return &zstdFrames{r: bufio.NewReaderSize(r, 1<<20)}
+}
+
+// Read n bytes appending them to the current frame.
+func (z *zstdFrames) read(n int) ([]byte, error) {
+	start := len(z.frame)
+	z.frame = append(z.frame, make([]byte, n)...)
+	if _, err := io.ReadFull(z.r, z.frame[start:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return z.frame[start:], nil
+}
+
+func (z *zstdFrames) next() ([]byte, error) {
+	for {
+		z.frame = nil
+		if _, err := z.r.Peek(1); err != nil {
+			return nil, err
+		}
+
+		magic, err := z.read(4)
+		if err != nil {
+			return nil, err
+		}
+		switch m := binary.LittleEndian.Uint32(magic); {
+		case m&0xfffffff0 == zstdSkippableMagic:
+			size, err := z.read(4)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := z.r.Discard(int(binary.LittleEndian.Uint32(size))); err != nil {
+				return nil, err
+			}
+			continue
+		case m != zstdMagic:
+			return nil, fmt.Errorf("invalid zstd frame magic: %x", m)
+		}
+
+		descriptor, err := z.read(1)
+		if err != nil {
+			return nil, err
+		}
+		singleSegment := descriptor[0]&0x20 != 0
+		checksum := descriptor[0]&0x04 != 0
+
+		// Window descriptor, dictionary id and frame content size
+		headerSize := [4]int{0, 1, 2, 4}[descriptor[0]&0x03]
+		if !singleSegment {
+			headerSize++
+		}
+		switch descriptor[0] >> 6 {
+		case 0:
+			if singleSegment {
+				headerSize++
+			}
+		case 1:
+			headerSize += 2
+		case 2:
+			headerSize += 4
+		case 3:
+			headerSize += 8
+		}
+		if _, err := z.read(headerSize); err != nil {
+			return nil, err
+		}
+
+		for last := false; !last; {
+			header, err := z.read(3)
+			if err != nil {
+				return nil, err
+			}
+			block := uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16
+			last = block&1 != 0
+			size := int(block >> 3)
+			switch (block >> 1) & 3 {
+			case 1:
+				// RLE block of a single byte
+				size = 1
+			case 3:
+				return nil, fmt.Errorf("invalid zstd block type")
+			}
+			if _, err := z.read(size); err != nil {
+				return nil, err
+			}
+			if len(z.frame) > maxChunkSize {
+				return nil, errChunkTooLarge
+			}
+		}
+
+		if checksum {
+			if _, err := z.read(4); err != nil {
+				return nil, err
+			}
+		}
+
+		return z.frame, nil
+	}
+}
+
+func (z *zstdFrames) remaining() io.Reader {
+	return io.MultiReader(bytes.NewReader(z.frame), z.r)
+}
+
+// Splits multi-stream bzip2 data (ie pbzip2 or lbzip2 output) at the byte aligned stream headers.
+type bzip2Streams struct {
+	r   io.Reader
+	buf []byte
+	eof bool
+}
+
+func newBzip2Streams(r io.Reader) *bzip2Streams {
+	return &bzip2Streams{r: r}
+}
+
+// Stream header `BZh[1-9]` followed by the block magic (BCD pi).
+func bzip2StreamStart(buf []byte, from int) int {
+	blockMagic := []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	for from < len(buf) {
+		idx := bytes.Index(buf[from:], magicBytes[".bz2"])
+		if idx < 0 {
+			return -1
+		}
+		idx += from
+		if idx+10 > len(buf) {
+			return -1
+		}
+		if buf[idx+3] >= '1' && buf[idx+3] <= '9' && bytes.Equal(buf[idx+4:idx+10], blockMagic) {
+			return idx
+		}
+		from = idx + 1
+	}
+
+	return -1
+}
+
+func (s *bzip2Streams) next() ([]byte, error) {
+	searched := 1
+	read := make([]byte, 1<<20)
+	for {
+		if idx := bzip2StreamStart(s.buf, searched); idx > 0 {
+			chunk := s.buf[:idx]
+			s.buf = append([]byte(nil), s.buf[idx:]...)
+			return chunk, nil
+		}
+		if s.eof {
+			if len(s.buf) == 0 {
+				return nil, io.EOF
+			}
+			chunk := s.buf
+			s.buf = nil
+			return chunk, nil
+		}
+		if len(s.buf) > maxChunkSize {
+			return nil, errChunkTooLarge
+		}
+
+		// Headers can be split over reads
+		searched = max(len(s.buf)-9, 1)
+		n, err := s.r.Read(read)
+		s.buf = append(s.buf, read[:n]...)
+		if errors.Is(err, io.EOF) {
+			s.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *bzip2Streams) remaining() io.Reader {
+	return io.MultiReader(bytes.NewReader(s.buf), s.r)
+}
@@ -3,17 +3,21 @@ package pgn
 import (
 	"context"
 	"fmt"
+	"io"
 	"math"
-	"regexp"
 	"strings"
 	"time"
 )
 
-func NewPGNParser(source Source, filtered bool) (*Parser, error) {
+var gameResults = map[string]bool{"1-0": true, "0-1": true, "1/2-1/2": true, "*": true}
+
+// Games are selected by filter, a nil filter keeps all games.
+func NewPGNParser(source Source, filter Filter) (*Parser, error) {
 	pp := &Parser{
-		source:   source,
-		clock:    time.Now(),
-		filtered: filtered,
+		source:  source,
+		clock:   time.Now(),
+		filter:  filter,
+		tempPGN: &PGN{},
 	}
 
 	err := pp.source.Open()
@@ -24,12 +28,17 @@ func NewPGNParser(source Source, filtered bool) (*Parser, error) {
 	return pp, nil
 }
 
+// Parse PGN from an io.Reader ie stdin or an in-memory string. See ReaderPGN.
+func NewPGNParserFromReader(r io.Reader, filter Filter) (*Parser, error) {
+	return NewPGNParser(NewReaderPGN(r), filter)
+}
+
 // Scan the PGN file for the next game meeting the criteria defined by filters. The game can be accessed by calling the PGN method.
+// A game ends with its game termination marker (result) or, if it has none, where the tag section of the next game starts.
 func (pp *Parser) Scan(ctx context.Context) bool {
 	pp.pgn = nil
-	pp.tempPGN = &PGN{}
 	if pp.nextLine != "" {
-		pp.tempPGN.AddTag(parseTag(pp.nextLine))
+		pp.addTag(pp.nextLine)
 		pp.nextLine = ""
 	}
 	for pp.source.Scan() {
@@ -45,63 +54,159 @@ func (pp *Parser) Scan(ctx context.Context) bool {
 			if strings.TrimSpace(line) == "" {
 				continue
 			}
-			if isTag(line) {
-				pp.tag, pp.value = parseTag(line)
-				if pp.filtered && !pp.skipping {
-					pp.skipping = !PreFilter(pp.tag, pp.value)
+			if !pp.inComment {
+				// Escape lines
+				if strings.HasPrefix(line, "%") {
+					continue
 				}
-				if pp.tag == TAG_EVENT && pp.tempPGN.Event != "" {
-					if pp.skipping {
-						pp.tempPGN = &PGN{Event: pp.value}
-						pp.skipping = false
-					} else {
-						pp.pgn = pp.tempPGN
+				if isTag(line) {
+					if pp.inMovetext && pp.endGame() {
 						pp.nextLine = line
-						pp.gameCount++
 						return true
 					}
+					pp.addTag(line)
+					continue
 				}
+			}
 
-				pp.tempPGN.AddTag(pp.tag, pp.value)
-			} else {
-				pp.tempPGN.Moves += line
+			// Keep line breaks as they end `;` comments and start `%` escape lines
+			if !pp.skipping {
+				pp.tempPGN.Moves += line + "\n"
+			}
+			pp.inGame, pp.inMovetext = true, true
+			if pp.endsGame(line) && pp.endGame() {
+				return true
 			}
 		}
 	}
 
-	if pp.pgn == nil && pp.tempPGN.Event != "" {
+	// Last game without a result, incomplete if reading failed
+	return pp.source.Err() == nil && pp.inGame && pp.endGame()
+}
+
+func (pp *Parser) addTag(line string) {
+	for _, pair := range parseTags(line) {
+		pp.tag, pp.value = pair.Tag, pair.Value
+		if pp.filter != nil && !pp.skipping {
+			pp.skipping = !pp.filter.MatchTag(pp.tag, pp.value)
+		}
+		pp.tempPGN.AddTag(pp.tag, pp.value)
+	}
+	pp.inGame = true
+}
+
+// Complete the current game and start a new one. Reports whether the completed game is selected.
+func (pp *Parser) endGame() bool {
+	selected := !pp.skipping && pp.tempPGN.isSelected() && (pp.filter == nil || pp.filter.MatchGame(pp.tempPGN))
+	if selected {
 		pp.pgn = pp.tempPGN
-		pp.tempPGN = nil
 		pp.gameCount++
-		return true
 	}
+	pp.tempPGN = &PGN{}
+	pp.skipping, pp.inGame, pp.inMovetext, pp.inComment = false, false, false, false
 
-	return false
+	return selected
+}
+
+// Whether the movetext line ends with a game termination marker. Tracks brace comments spanning lines, as tags and results
+// inside a comment are not part of the game structure.
+func (pp *Parser) endsGame(line string) bool {
+	var ends bool
+	ends, pp.inComment = scanMovetext(line, pp.inComment)
+
+	return ends
+}
+
+// Whether the movetext line ends with a game termination marker and whether a brace comment is open at its end.
+func scanMovetext(line string, inComment bool) (bool, bool) {
+	var text strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; {
+		case inComment:
+			inComment = ch != '}'
+		case ch == '{':
+			inComment = true
+			text.WriteByte(' ')
+		case ch == ';':
+			i = len(line)
+		default:
+			text.WriteByte(ch)
+		}
+	}
+	fields := strings.Fields(text.String())
+
+	return len(fields) > 0 && gameResults[fields[len(fields)-1]], inComment
 }
 
 func (pp *Parser) PGN() *PGN {
 	return pp.pgn
 }
 
-func (pp *Parser) Progress(_ bool) {
+func (pp *Parser) Progress(final bool) {
+	if pp.group != nil {
+		pp.group.update(pp, final)
+		return
+	}
+
 	output := fmt.Sprintf("games: %d size: %v done: %.2f%%", pp.gameCount, pp.source.Size(), 100*math.Min(1, float64(pp.source.BytesRead())/float64(pp.source.Size())))
+	// Streams of unknown size
+	if pp.source.Size() == 0 {
+		output = fmt.Sprintf("games: %d read: %v", pp.gameCount, pp.source.BytesRead())
+	}
 	fmt.Printf("%s\r", output)
 }
 
+// The read error that ended scanning, nil at the end of input.
+func (pp *Parser) Err() error {
+	return pp.source.Err()
+}
+
 func (pp *Parser) Close() error {
 	return pp.source.Close()
 }
 
+// Get the main line movetext without comments, variations, NAGs and move marks ie `1. e4 e5 2. Nf3 Nc6 1-0`.
+// Moves are normalized like tokens, without check and mate indicators and with `0-0` castling as `O-O`. The result is kept, `*` included.
 func (pgn *PGN) RemoveAnnotations() string {
-	// Removes: move number continuation after variation `3...`, variation `(*)`, comments `{*}`, special characters `[+#?!]`
-	re := regexp.MustCompile(`\d+\.\.\.|\([^()]*\)|\{[^{}]*\}|[!?+#*]`)
-	whiteSpace := regexp.MustCompile(`\s+`)
-	text := re.ReplaceAllLiteralString(pgn.Moves, "")
-	text = whiteSpace.ReplaceAllLiteralString(text, " ")
-	return text
+	var text strings.Builder
+	depth, lastNumber := 0, ""
+	for _, token := range pgn.Tokens() {
+		switch token.Type {
+		case TOKEN_VARIATION_START:
+			depth++
+			continue
+		case TOKEN_VARIATION_END:
+			depth = max(depth-1, 0)
+			continue
+		case TOKEN_COMMENT, TOKEN_NAG:
+			continue
+		}
+		// Skip the move number continuation after a comment or variation ie `3...`
+		if depth > 0 || (token.Type == TOKEN_MOVE_NUMBER && token.Value == lastNumber) {
+			continue
+		}
+
+		if text.Len() > 0 {
+			text.WriteByte(' ')
+		}
+		text.WriteString(token.Value)
+		if token.Type == TOKEN_MOVE_NUMBER {
+			text.WriteByte('.')
+			lastNumber = token.Value
+		}
+	}
+
+	return text.String()
 }
 
+// Get all comments including those in variations, each enclosed in braces.
 func (pgn *PGN) GetAnnotations() []string {
-	re := regexp.MustCompile(`\{[^{}]*\}`)
-	return re.FindAllString(pgn.Moves, -1)
+	annotations := make([]string, 0)
+	for _, token := range pgn.Tokens() {
+		if token.Type == TOKEN_COMMENT {
+			annotations = append(annotations, "{"+token.Value+"}")
+		}
+	}
+
+	return annotations
 }
@@ -1,13 +1,11 @@
 package pgn
 
 import (
-	"bufio"
-
 	"github.com/inhies/go-bytesize"
 )
 
 type PlainPGN struct {
-	pgn    *bufio.Scanner
+	pgn    *LineReader
 	reader *ByteCountingReader
 	close  closeFn
 	path   string
@@ -30,7 +28,7 @@ func (s *PlainPGN) Open() error {
 	s.close = close
 	s.reader = &ByteCountingReader{reader: reader}
 	s.size = size
-	s.pgn = bufio.NewScanner(bufio.NewReader(s.reader))
+	s.pgn = NewLineReader(s.reader)
 
 	return nil
 }
@@ -47,6 +45,10 @@ func (s *PlainPGN) Text() string {
 	return s.pgn.Text()
 }
 
+func (s *PlainPGN) Err() error {
+	return s.pgn.Err()
+}
+
 func (s *PlainPGN) Size() bytesize.ByteSize {
 	return s.size
 }
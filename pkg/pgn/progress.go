@@ -0,0 +1,78 @@
+package pgn
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/inhies/go-bytesize"
+)
+
+// Aggregates the progress of parsers running concurrently into a single line.
+// Parsers report their own progress so that sources are only accessed from the goroutine scanning them.
+type ProgressGroup struct {
+	clock time.Time
+	stats map[*Parser]progressStats
+	total int
+	lock  sync.Mutex
+}
+
+type progressStats struct {
+	games int
+	read  bytesize.ByteSize
+	size  bytesize.ByteSize
+	done  bool
+}
+
+// Group for the total number of sources to be parsed.
+func NewProgressGroup(total int) *ProgressGroup {
+	return &ProgressGroup{
+		clock: time.Now(),
+		stats: make(map[*Parser]progressStats),
+		total: total,
+	}
+}
+
+// Report the parser progress to the group instead of printing it.
+func (pp *Parser) SetProgressGroup(pg *ProgressGroup) {
+	pp.group = pg
+}
+
+func (pg *ProgressGroup) update(pp *Parser, done bool) {
+	pg.lock.Lock()
+	defer pg.lock.Unlock()
+	pg.stats[pp] = progressStats{
+		games: pp.gameCount,
+		read:  pp.source.BytesRead(),
+		size:  pp.source.Size(),
+		done:  done,
+	}
+
+	if done || time.Since(pg.clock) > time.Second {
+		pg.print()
+		pg.clock = time.Now()
+	}
+}
+
+// The done percentage is only shown when the size of every source is known.
+func (pg *ProgressGroup) print() {
+	var games, finished int
+	var read, size bytesize.ByteSize
+	unknownSize := false
+	for _, stats := range pg.stats {
+		games += stats.games
+		read += stats.read
+		size += stats.size
+		unknownSize = unknownSize || stats.size == 0
+		if stats.done {
+			finished++
+		}
+	}
+
+	output := fmt.Sprintf("sources: %d/%d games: %d size: %v done: %.2f%%", finished, pg.total, games, size, 100*math.Min(1, float64(read)/float64(size)))
+	if unknownSize {
+		output = fmt.Sprintf("sources: %d/%d games: %d read: %v", finished, pg.total, games, read)
+	}
+	fmt.Printf("%s\r", output)
+}
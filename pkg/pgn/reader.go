@@ -0,0 +1,91 @@
+package pgn
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/dsnet/compress/bzip2"
+	"github.com/inhies/go-bytesize"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Reads PGN from an io.Reader ie stdin or an in-memory string. Compressed streams are detected by their magic bytes.
+type ReaderPGN struct {
+	source io.Reader
+	pgn    *LineReader
+	reader *ByteCountingReader
+	size   bytesize.ByteSize
+}
+
+func NewReaderPGN(r io.Reader) *ReaderPGN {
+	return &ReaderPGN{
+		source: r,
+	}
+}
+
+func (s *ReaderPGN) Open() error {
+	// The size is only known for regular files ie stdin redirected from a file
+	if file, ok := s.source.(*os.File); ok {
+		if stat, err := file.Stat(); err == nil && stat.Mode().IsRegular() {
+			s.size = bytesize.ByteSize(stat.Size())
+		}
+	}
+
+	buffered := bufio.NewReader(s.source)
+	header, _ := buffered.Peek(8)
+
+	var reader io.Reader = buffered
+	var err error
+	switch {
+	case bytes.HasPrefix(header, magicBytes[".zst"]):
+		reader, err = zstd.NewReader(buffered)
+	case bytes.HasPrefix(header, magicBytes[".bz2"]):
+		reader, err = bzip2.NewReader(buffered, nil)
+	case bytes.HasPrefix(header, magicBytes[".gz"]):
+		reader, err = gzip.NewReader(buffered)
+	case bytes.HasPrefix(header, magicBytes[".xz"]):
+		reader, err = xz.NewReader(buffered)
+	}
+	if err != nil {
+		return err
+	}
+	if reader != io.Reader(buffered) {
+		// Decompressed size is unknown
+		s.size = 0
+	}
+
+	s.reader = &ByteCountingReader{reader: reader}
+	s.pgn = NewLineReader(s.reader)
+
+	return nil
+}
+
+// The reader is owned by the caller and not closed.
+func (s *ReaderPGN) Close() error {
+	return nil
+}
+
+func (s *ReaderPGN) Scan() bool {
+	return s.pgn.Scan()
+}
+
+func (s *ReaderPGN) Text() string {
+	return s.pgn.Text()
+}
+
+func (s *ReaderPGN) Err() error {
+	return s.pgn.Err()
+}
+
+// Zero if the size of the data is unknown.
+func (s *ReaderPGN) Size() bytesize.ByteSize {
+	return s.size
+}
+
+func (s *ReaderPGN) BytesRead() bytesize.ByteSize {
+	return s.reader.bytesRead
+}
@@ -0,0 +1,164 @@
+package pgn
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Constructs a source reading the path. The source is opened by the parser.
+type SourceConstructor func(path string) Source
+
+// Source constructors by file extension, url scheme and magic bytes. Packages can register their own input formats in init.
+var registry = struct {
+	extensions map[string]SourceConstructor
+	schemes    map[string]SourceConstructor
+	magic      []magicSource
+	lock       sync.RWMutex
+}{
+	extensions: make(map[string]SourceConstructor),
+	schemes:    make(map[string]SourceConstructor),
+}
+
+type magicSource struct {
+	constructor SourceConstructor
+	magic       []byte
+}
+
+// Magic bytes of the supported compression formats by their extension.
+var magicBytes = map[string][]byte{
+	".zst": {0x28, 0xb5, 0x2f, 0xfd},
+	".bz2": []byte("BZh"),
+	".gz":  {0x1f, 0x8b},
+	".xz":  {0xfd, '7', 'z', 'X', 'Z', 0x00},
+	".zip": []byte("PK\x03\x04"),
+}
+
+func init() {
+	builtin := map[string]SourceConstructor{
+		".zst": func(path string) Source {
+			if DecodeWorkers > 1 {
+				return NewParallelZstPGN(path, DecodeWorkers)
+			}
+			return NewZstPGN(path)
+		},
+		".bz2": func(path string) Source {
+			if DecodeWorkers > 1 {
+				return NewParallelBzip2PGN(path, DecodeWorkers)
+			}
+			return NewBzip2PGN(path)
+		},
+		".gz":  func(path string) Source { return NewGzipPGN(path) },
+		".xz":  func(path string) Source { return NewXzPGN(path) },
+		".zip": func(path string) Source { return NewZipPGN(path) },
+		".pgn": func(path string) Source { return NewPlainPGN(path) },
+	}
+	for ext, constructor := range builtin {
+		RegisterExtension(ext, constructor)
+		if magic, ok := magicBytes[ext]; ok {
+			RegisterMagic(magic, constructor)
+		}
+	}
+
+	RegisterScheme("lichess", func(path string) Source {
+		return NewLichessPGN(strings.TrimPrefix(path, "lichess:"), Lichess)
+	})
+	RegisterScheme("chesscom", func(path string) Source {
+		return NewChessComPGN(strings.TrimPrefix(path, "chesscom:"), ChessComOptions{})
+	})
+}
+
+// Register a source for files and urls ending in the extension ie `.pgn.zst`. The longest matching extension is used.
+func RegisterExtension(ext string, constructor SourceConstructor) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.extensions[strings.ToLower(ext)] = constructor
+}
+
+// Register a source for inputs in the form `<scheme>:<rest>`. The constructor receives the whole input.
+func RegisterScheme(scheme string, constructor SourceConstructor) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.schemes[strings.ToLower(scheme)] = constructor
+}
+
+// Register a source for local files starting with the magic bytes. Magic bytes take precedence over the extension so that misnamed files work.
+// The longest matching signature is used.
+func RegisterMagic(magic []byte, constructor SourceConstructor) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.magic = append(registry.magic, magicSource{magic: magic, constructor: constructor})
+	sort.SliceStable(registry.magic, func(i, j int) bool {
+		return len(registry.magic[i].magic) > len(registry.magic[j].magic)
+	})
+}
+
+func schemeConstructor(path string) SourceConstructor {
+	scheme, _, ok := strings.Cut(path, ":")
+	if !ok {
+		return nil
+	}
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	return registry.schemes[strings.ToLower(scheme)]
+}
+
+func sourceFromPath(path string) (Source, error) {
+	if constructor := sniffSource(path); constructor != nil {
+		return constructor(path), nil
+	}
+
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	var match string
+	lower := strings.ToLower(path)
+	for ext := range registry.extensions {
+		if strings.HasSuffix(lower, ext) && len(ext) > len(match) {
+			match = ext
+		}
+	}
+	if match == "" {
+		return nil, fmt.Errorf("unsupported file format")
+	}
+
+	return registry.extensions[match](path), nil
+}
+
+// Detect the format of a local file by its magic bytes. Text starting like a PGN is detected as plain PGN.
+// Returns nil for urls and unknown formats.
+func sniffSource(path string) SourceConstructor {
+	if isUrl(path) {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	header := make([]byte, 512)
+	n, _ := io.ReadFull(file, header)
+	header = header[:n]
+
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	for _, ms := range registry.magic {
+		if bytes.HasPrefix(header, ms.magic) {
+			return ms.constructor
+		}
+	}
+
+	// Directories walked recursively may contain other text files, require the first line to be a tag pair
+	text := strings.TrimLeft(strings.TrimPrefix(string(header), "\ufeff"), " \t\r\n")
+	if line, _, _ := strings.Cut(text, "\n"); isTag(line) {
+		return registry.extensions[".pgn"]
+	}
+
+	return nil
+}
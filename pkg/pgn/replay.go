@@ -0,0 +1,51 @@
+package pgn
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+// Replay the main line from the starting position of the game. Visit is called with the starting position and the position after
+// every move, replaying stops when it returns false. Returns an error at the first invalid or illegal move.
+func (pgn *PGN) Replay(visit func(b *board.Board) bool) error {
+	b := board.NewBoard(pgn.StartFEN())
+	var chess960 *Chess960
+	if pgn.IsVariant(VARIANT_CHESS960) {
+		var err error
+		if chess960, b, err = NewChess960(pgn.Tag(TAG_FEN)); err != nil {
+			return err
+		}
+	}
+	if !visit(b) {
+		return nil
+	}
+
+	for i, san := range pgn.MainLine().SANs {
+		if chess960 != nil {
+			var err error
+			if b, _, err = chess960.Play(b, san); err != nil {
+				return fmt.Errorf("ply %d: %w", i+1, err)
+			}
+		} else {
+			move, err := SANToMove(b, san)
+			if err != nil {
+				return fmt.Errorf("ply %d: %w", i+1, err)
+			}
+			b.MakeMove(move)
+		}
+		if !visit(b) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Position identity of a FEN: piece placement, side to move and castling rights. Move counters and en passant are ignored.
+func positionKey(fen string) string {
+	fields := strings.Fields(fen)
+
+	return strings.Join(fields[:min(len(fields), 3)], " ")
+}
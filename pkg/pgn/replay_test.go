@@ -0,0 +1,53 @@
+package pgn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+func TestReplay(t *testing.T) {
+	setUp := testPGN("SetUp", "1", "FEN", "4k3/8/8/8/8/8/8/4K2R w K - 0 1")
+	setUp.Moves = "1. O-O Kd7 *"
+	tests := []struct {
+		name      string
+		game      *PGN
+		positions int
+		err       string
+	}{
+		{"main line", &PGN{Moves: "1. e4 e5 (1... c5 2. Nf3) 2. Nf3 Nc6 3. Bb5 a6 *"}, 7, ""},
+		{"empty", &PGN{Moves: "*"}, 1, ""},
+		{"illegal move", &PGN{Moves: "1. e4 e5 2. Ke3 *"}, 3, "ply 3"},
+		{"invalid move", &PGN{Moves: "1. e4 xyz *"}, 2, "ply 2"},
+		{"set up", setUp, 3, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positions := 0
+			err := tt.game.Replay(func(_ *board.Board) bool {
+				positions++
+				return true
+			})
+			if (err != nil) != (tt.err != "") || (err != nil && !strings.HasPrefix(err.Error(), tt.err)) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+			if positions != tt.positions {
+				t.Errorf("positions = %d, want %d", positions, tt.positions)
+			}
+		})
+	}
+}
+
+func TestReplayStops(t *testing.T) {
+	game := &PGN{Moves: "1. e4 e5 2. Nf3 Nc6 *"}
+	positions := 0
+	_ = game.Replay(func(_ *board.Board) bool {
+		positions++
+		return positions < 2
+	})
+	if positions != 2 {
+		t.Errorf("positions = %d, want 2", positions)
+	}
+}
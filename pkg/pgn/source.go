@@ -1,12 +1,12 @@
 package pgn
 
 import (
-	"fmt"
 	"io"
-	"net/http"
+	"io/fs"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/inhies/go-bytesize"
@@ -17,6 +17,8 @@ type Source interface {
 	Close() error
 	Scan() bool
 	Text() string
+	// The error that ended the scan, nil at the end of input
+	Err() error
 	// The size (or estimated size in case of archives) of the data
 	Size() bytesize.ByteSize
 	// Fraction of data that has been read. Bounded by 1 in case of bad estimates
@@ -40,12 +42,7 @@ type closeFn func() error
 
 func openSource(path string) (io.Reader, bytesize.ByteSize, closeFn, error) {
 	if isUrl(path) {
-		r, err := http.Get(path)
-		if err != nil {
-			return nil, 0, nil, err
-		}
-
-		return r.Body, bytesize.ByteSize(r.ContentLength), r.Body.Close, nil
+		return openHTTP(path)
 	}
 
 	file, err := os.Open(path)
@@ -61,77 +58,171 @@ func openSource(path string) (io.Reader, bytesize.ByteSize, closeFn, error) {
 	return file, bytesize.ByteSize(stat.Size()), file.Close, err
 }
 
-func sourceFromPath(path string) (Source, error) {
-	switch filepath.Ext(path) {
-	case ".zst":
-		return NewZstPGN(path), nil
-	case ".bz2":
-		return NewBzip2PGN(path), nil
-	case ".pgn":
-		return NewPlainPGN(path), nil
+// Parse a comma delimited list of inputs into sources. An input can be:
+//   - a file or a directory, which is walked recursively
+//   - a glob pattern, `**` matches any number of directories ie `lichess/**/*.pgn.zst`
+//   - `@<list.txt>` a file listing one input per line, relative paths are relative to the list file
+//   - a url, `lichess:<user>`, `chesscom:<user>` or `-` for stdin
+//
+// Sources are ordered as listed with directories and globs in lexical order. Duplicate paths are only added once.
+func ParsePath(pgnPath string) ([]Source, error) {
+	sl := &sourceList{
+		sources: make([]Source, 0),
+		seen:    make(map[string]bool),
+	}
+
+	for _, path := range strings.Split(pgnPath, ",") {
+		sl.addInput(strings.TrimSpace(path))
+	}
+
+	return sl.sources, nil
+}
+
+type sourceList struct {
+	seen    map[string]bool
+	sources []Source
+}
+
+func (sl *sourceList) addInput(path string) {
+	switch {
+	case path == "":
+	case path == "-":
+		sl.add(path, NewReaderPGN(os.Stdin))
+	case schemeConstructor(path) != nil:
+		sl.add(path, schemeConstructor(path)(path))
+	case isUrl(path):
+		if source, err := sourceFromPath(path); err == nil {
+			sl.add(path, source)
+		}
+	case strings.HasPrefix(path, "@"):
+		sl.addList(strings.TrimPrefix(path, "@"))
+	case strings.ContainsAny(path, "*?["):
+		for _, match := range expandGlob(path) {
+			sl.addFile(match)
+		}
 	default:
-		return nil, fmt.Errorf("unsupported file format")
+		sl.addFile(path)
 	}
 }
 
-func ParsePath(pgnPath string) ([]Source, error) {
-	files := make([]Source, 0)
-	paths := strings.Split(pgnPath, ",")
+func (sl *sourceList) add(key string, source Source) {
+	if sl.seen[key] {
+		return
+	}
+	sl.seen[key] = true
+	sl.sources = append(sl.sources, source)
+}
 
-	for _, path := range paths {
-		path = strings.TrimSpace(path)
+// Add a file or every file of a directory tree in lexical order. Unsupported files are ignored.
+func (sl *sourceList) addFile(path string) {
+	_ = filepath.WalkDir(path, func(subPath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
 
-		if isUrl(path) {
-			if source, err := sourceFromPath(path); err == nil {
-				files = append(files, source)
-			}
-		} else {
-			file, err := os.Open(path)
-			if err != nil {
-				continue
+		key, err := filepath.Abs(subPath)
+		if err != nil {
+			key = subPath
+		}
+		if sl.seen[key] {
+			return nil
+		}
+		source, err := sourceFromPath(subPath)
+		if err != nil {
+			return nil
+		}
+		if _, plain := source.(*PlainPGN); plain && SplitChunks > 1 {
+			if chunks, err := SplitPlainPGN(subPath, SplitChunks); err == nil {
+				sl.seen[key] = true
+				sl.sources = append(sl.sources, chunks...)
+				return nil
 			}
-			defer file.Close()
+		}
+		sl.add(key, source)
+		return nil
+	})
+}
 
-			fileInfo, err := file.Stat()
-			if err != nil {
-				continue
-			}
+// Inputs listed one per line. Blank lines and lines starting with `#` are ignored.
+func (sl *sourceList) addList(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
 
-			if fileInfo.IsDir() {
-				if dirSources, err := dataSourceFromDir(path); err == nil {
-					files = append(files, dirSources...)
-				}
-			} else {
-				if source, err := sourceFromPath(path); err == nil {
-					files = append(files, source)
-				}
-			}
+	lines := NewLineReader(file)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
+		if !isUrl(line) && line != "-" && !strings.Contains(line, ":") && !strings.HasPrefix(line, "@") && !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(path), line)
+		}
+		sl.addInput(line)
 	}
-	return files, nil
 }
 
+// Absolute file paths are valid request URIs as well, a url requires a scheme and a host.
 func isUrl(path string) bool {
-	_, err := url.ParseRequestURI(path)
-	return err == nil
+	u, err := url.ParseRequestURI(path)
+	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
-func dataSourceFromDir(path string) ([]Source, error) {
-	subfiles, err := os.ReadDir(path)
-	sc := make([]Source, 0)
-	if err != nil {
-		return nil, err
+// Expand a glob pattern into the matching paths in lexical order. Unlike filepath.Glob `**` matches any number of directories.
+func expandGlob(pattern string) []string {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	if !strings.Contains(pattern, "**") {
+		matches, _ := filepath.Glob(filepath.FromSlash(pattern))
+		sort.Strings(matches)
+		return matches
 	}
 
-	for _, subfile := range subfiles {
-		// Ignore subdirectories
-		if subfile.IsDir() {
-			continue
+	// Walk from the longest directory prefix without wildcards
+	segments := strings.Split(pattern, "/")
+	static := 0
+	for static < len(segments)-1 && !strings.ContainsAny(segments[static], "*?[") {
+		static++
+	}
+	root := strings.Join(segments[:static], "/")
+	switch {
+	case root == "" && strings.HasPrefix(pattern, "/"):
+		root = "/"
+	case root == "":
+		root = "."
+	}
+
+	matches := make([]string, 0)
+	_ = filepath.WalkDir(filepath.FromSlash(root), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if matchSegments(segments, strings.Split(filepath.ToSlash(path), "/")) {
+			matches = append(matches, path)
 		}
+		return nil
+	})
 
-		if source, err := sourceFromPath(filepath.Join(path, subfile.Name())); err == nil {
-			sc = append(sc, source)
+	return matches
+}
+
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
 		}
+		return false
 	}
-	return sc, nil
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := filepath.Match(pattern[0], name[0])
+
+	return ok && matchSegments(pattern[1:], name[1:])
 }
@@ -2,29 +2,223 @@ package pgn
 
 import (
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/likeawizard/tofiks/pkg/board"
 )
 
-var tagMatch = regexp.MustCompile(`\[(?P<tag>\w+)\s"(?P<value>.*[^"])"\]`)
+// A line of one or more tag pairs ie `[White "a"] [Black "b"]`. Values may contain escaped quotes and backslashes.
+var tagLineMatch = regexp.MustCompile(`^\s*(?:\[\w+\s+"(?:[^"\\]|\\.)*"\s*\]\s*)+$`)
+
+var tagMatch = regexp.MustCompile(`\[(?P<tag>\w+)\s+"(?P<value>(?:[^"\\]|\\.)*)"\s*\]`)
+
+// A single tag pair with unescaped quotes in its value ie `[Event "The "Open""]`, as written by some tools.
+var looseTagMatch = regexp.MustCompile(`^\s*\[(?P<tag>\w+)\s+"(?P<value>.*)"\s*\]\s*$`)
 
 func isTag(line string) bool {
-	return tagMatch.MatchString(line)
+	return tagLineMatch.MatchString(line) || looseTagMatch.MatchString(line)
 }
 
-func parseTag(line string) (tag Tag, value string) {
-	m := tagMatch.FindStringSubmatch(line)
-	tag = Tag(m[tagMatch.SubexpIndex("tag")])
-	value = m[tagMatch.SubexpIndex("value")]
+var tagUnescape = strings.NewReplacer(`\"`, `"`, `\\`, `\`)
+
+// The tag pairs of a tag line.
+func parseTags(line string) []TagPair {
+	re, matches := tagMatch, tagMatch.FindAllStringSubmatch(line, -1)
+	if !tagLineMatch.MatchString(line) {
+		re, matches = looseTagMatch, looseTagMatch.FindAllStringSubmatch(line, -1)
+	}
 
-	return
+	pairs := make([]TagPair, len(matches))
+	for i, m := range matches {
+		pairs[i] = TagPair{Tag: Tag(m[re.SubexpIndex("tag")]), Value: tagUnescape.Replace(m[re.SubexpIndex("value")])}
+	}
+
+	return pairs
 }
 
+// Add a tag pair keeping the order of the tag section. A repeated tag replaces the value of the earlier one.
 func (pgn *PGN) AddTag(tag Tag, value string) *PGN {
-	switch tag {
-	case TAG_EVENT:
-		pgn.Event = value
-	case TAG_RESULT:
-		pgn.Result = value
+	for i := range pgn.Tags {
+		if pgn.Tags[i].Tag == tag {
+			pgn.Tags[i].Value = value
+			return pgn
+		}
 	}
+	pgn.Tags = append(pgn.Tags, TagPair{Tag: tag, Value: value})
 
 	return pgn
 }
+
+// The value of the tag, empty if the game does not have it.
+func (pgn *PGN) Tag(tag Tag) string {
+	value, _ := pgn.LookupTag(tag)
+	return value
+}
+
+func (pgn *PGN) LookupTag(tag Tag) (string, bool) {
+	for _, pair := range pgn.Tags {
+		if pair.Tag == tag {
+			return pair.Value, true
+		}
+	}
+
+	return "", false
+}
+
+func (pgn *PGN) Event() string {
+	return pgn.Tag(TAG_EVENT)
+}
+
+func (pgn *PGN) Site() string {
+	return pgn.Tag(TAG_SITE)
+}
+
+// Date the game started, false if the date or any of its parts is unknown ie `2023.??.??`.
+func (pgn *PGN) Date() (time.Time, bool) {
+	return parseDate(pgn.Tag(TAG_DATE), "")
+}
+
+func (pgn *PGN) Round() string {
+	return pgn.Tag(TAG_ROUND)
+}
+
+func (pgn *PGN) White() string {
+	return pgn.Tag(TAG_WHITE)
+}
+
+func (pgn *PGN) Black() string {
+	return pgn.Tag(TAG_BLACK)
+}
+
+func (pgn *PGN) Result() string {
+	return pgn.Tag(TAG_RESULT)
+}
+
+// Elo of the white player, false if the tag is missing or not a number ie `-` or `?`.
+func (pgn *PGN) WhiteElo() (int, bool) {
+	return parseElo(pgn.Tag(TAG_WHITE_ELO))
+}
+
+// Elo of the black player, false if the tag is missing or not a number ie `-` or `?`.
+func (pgn *PGN) BlackElo() (int, bool) {
+	return parseElo(pgn.Tag(TAG_BLACK_ELO))
+}
+
+// Time control of the first period, false if the game is untimed or the time control is unknown.
+func (pgn *PGN) TimeControl() (TimeControl, bool) {
+	return ParseTimeControl(pgn.Tag(TAG_TIMECONTROL))
+}
+
+func (pgn *PGN) Termination() string {
+	return pgn.Tag(TAG_TERMINATION)
+}
+
+func (pgn *PGN) ECO() string {
+	return pgn.Tag(TAG_ECO)
+}
+
+// Date and time (UTCTime tag) the game started in UTC, false if the date is unknown.
+func (pgn *PGN) UTCDate() (time.Time, bool) {
+	return parseDate(pgn.Tag(TAG_UTCDATE), pgn.Tag(TAG_UTCTIME))
+}
+
+// Normalized variant of the game, see NormalizeVariant.
+func (pgn *PGN) Variant() string {
+	return NormalizeVariant(pgn.Tag(TAG_VARIANT))
+}
+
+// Dates are in the `YYYY.MM.DD` format, times in `HH:MM:SS`.
+func parseDate(date, clock string) (time.Time, bool) {
+	t, err := time.Parse("2006.01.02", date)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if c, err := time.Parse("15:04:05", clock); err == nil {
+		t = t.Add(c.Sub(c.Truncate(24 * time.Hour)))
+	}
+
+	return t, true
+}
+
+func parseElo(value string) (int, bool) {
+	elo, err := strconv.Atoi(value)
+	return elo, err == nil && elo > 0
+}
+
+// Time control of a period `base+increment` in seconds.
+type TimeControl struct {
+	Base      int
+	Increment int
+}
+
+// Parse a TimeControl tag ie `300+3`, `600` or `40/7200:3600`, only the first period is kept. Untimed (`-`), unknown (`?`)
+// and sandclock (`*60`) time controls are not parsed.
+func ParseTimeControl(value string) (TimeControl, bool) {
+	period, _, _ := strings.Cut(value, ":")
+	// Moves per period ie `40/7200`
+	if _, seconds, ok := strings.Cut(period, "/"); ok {
+		period = seconds
+	}
+
+	base, increment, hasIncrement := strings.Cut(period, "+")
+	var tc TimeControl
+	var err error
+	if tc.Base, err = strconv.Atoi(base); err != nil {
+		return TimeControl{}, false
+	}
+	if hasIncrement {
+		if tc.Increment, err = strconv.Atoi(increment); err != nil {
+			return TimeControl{}, false
+		}
+	}
+
+	return tc, true
+}
+
+// Estimated game duration in seconds for a 60 move game.
+func (tc TimeControl) Seconds() int {
+	return tc.Base + 60*tc.Increment
+}
+
+// Variant names differ between sources ie `Chess960`, `Chess 960`, `Fischerandom`. Games without a variant tag are standard.
+func NormalizeVariant(value string) string {
+	switch strings.NewReplacer(" ", "", "-", "").Replace(strings.ToLower(value)) {
+	case "", "standard", "normal", "fromposition":
+		return VARIANT_STANDARD
+	case "chess960", "960", "fischerandom", "fischerrandom":
+		return VARIANT_CHESS960
+	default:
+		return value
+	}
+}
+
+// The color the player had in the game by matching any of the player's names against the White and Black tags.
+func (pgn *PGN) PlayerColor(names ...string) (int, bool) {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		switch {
+		case strings.EqualFold(pgn.White(), name):
+			return board.WHITE, true
+		case strings.EqualFold(pgn.Black(), name):
+			return board.BLACK, true
+		}
+	}
+
+	return 0, false
+}
+
+// Games with a `FEN` tag start from that position, which should be accompanied by `[SetUp "1"]`.
+func (pgn *PGN) IsSetUp() bool {
+	return pgn.Tag(TAG_SETUP) == "1" || pgn.Tag(TAG_FEN) != ""
+}
+
+// The starting position of the game as accepted by board.NewBoard.
+func (pgn *PGN) StartFEN() string {
+	if fen := pgn.Tag(TAG_FEN); fen != "" {
+		return fen
+	}
+
+	return "startpos"
+}
@@ -0,0 +1,65 @@
+package pgn
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		line  string
+		pairs []TagPair
+	}{
+		{`[White "Carlsen, Magnus"]`, []TagPair{{"White", "Carlsen, Magnus"}}},
+		{`  [Event ""]  `, []TagPair{{"Event", ""}}},
+		{`[White "a"] [Black "b"]`, []TagPair{{"White", "a"}, {"Black", "b"}}},
+		{`[White "a"][Black "b"][Result "1-0"]`, []TagPair{{"White", "a"}, {"Black", "b"}, {"Result", "1-0"}}},
+		{`[Annotator "The \"Fritz\" engine \\ v1"]`, []TagPair{{"Annotator", `The "Fritz" engine \ v1`}}},
+		{`[Event "The "Open""]`, []TagPair{{"Event", `The "Open"`}}},
+	}
+
+	for _, tt := range tests {
+		if !isTag(tt.line) {
+			t.Errorf("isTag(%q) = false", tt.line)
+		}
+		if pairs := parseTags(tt.line); !slices.Equal(pairs, tt.pairs) {
+			t.Errorf("parseTags(%q) = %q, want %q", tt.line, pairs, tt.pairs)
+		}
+	}
+
+	for _, line := range []string{`1. e4 e5`, `[White "a"] 1. e4`, `{[White "a"]}`, `[White a]`} {
+		if isTag(line) {
+			t.Errorf("isTag(%q) = true", line)
+		}
+	}
+}
+
+func TestParserTagLines(t *testing.T) {
+	input := `[Event "One"] [White "a"] [Black "b"]
+[Result "1-0"]
+
+1. e4 e5 1-0
+[Event "Two"][White "c"][Black "d"][Result "0-1"]
+1. d4 d5 0-1
+`
+	pp, err := NewPGNParserFromReader(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]TagPair{
+		{{"Event", "One"}, {"White", "a"}, {"Black", "b"}, {"Result", "1-0"}},
+		{{"Event", "Two"}, {"White", "c"}, {"Black", "d"}, {"Result", "0-1"}},
+	}
+	games := 0
+	for ; pp.Scan(context.Background()); games++ {
+		if games < len(want) && !slices.Equal(pp.PGN().Tags, want[games]) {
+			t.Errorf("game %d tags = %q, want %q", games, pp.PGN().Tags, want[games])
+		}
+	}
+	if games != len(want) {
+		t.Errorf("games = %d, want %d", games, len(want))
+	}
+}
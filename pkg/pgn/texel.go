@@ -9,36 +9,32 @@ import (
 	"github.com/likeawizard/tofiks/pkg/board"
 )
 
+var sanMatch = regexp.MustCompile(`^(?P<piece>[NBRQK])?(?P<disamb>[a-h]?[1-8]?)?(?P<capture>x?)(?P<target>[a-h][1-8])(?:=(?P<promo>[NBRQ]))?$`)
+
 func (pgn *PGN) GetFENs() []string {
 	result := "0.5"
 	fens := make([]string, 0)
-	switch pgn.Result {
+	switch pgn.Result() {
 	case "1-0":
 		result = "1"
 	case "0-1":
 		result = "0"
 	}
-	annotations := pgn.GetAnnotations()
-
 	// Based on cutechess annotations - filter out book moves and mates
-	validMoveAnnotation := regexp.MustCompile(`{[+-]\d+\.\d+.*?}`)
-
-	movesSAN := pgn.RemoveAnnotations()
+	validMoveAnnotation := regexp.MustCompile(`^[+-]\d+\.\d+`)
 
-	re := regexp.MustCompile(`\d+\.\s|\s*1-0|\s*0-1|\s*1\/2-1\/2`)
-	movesSAN = re.ReplaceAllLiteralString(movesSAN, "")
-	SANs := strings.Fields(movesSAN)
+	line := pgn.MainLine()
 
-	b := board.NewBoard("startpos")
+	b := board.NewBoard(pgn.StartFEN())
 
-	for i, san := range SANs {
+	for i, san := range line.SANs {
 		move, err := SANToMove(b, san)
 		if err != nil {
 			// log.Printf("move: %s pgn: %+v\n", san, *pgn)
 			break
 		}
 
-		if validMoveAnnotation.Match([]byte(annotations[i])) {
+		if validMoveAnnotation.MatchString(line.Comment(i)) {
 			fen := fmt.Sprintf("%s %s\n", result, b.ExportFEN())
 			fens = append(fens, fen)
 		}
@@ -50,25 +46,32 @@ func (pgn *PGN) GetFENs() []string {
 func SANToMove(b *board.Board, san string) (board.Move, error) {
 	switch {
 	// Castling moves
-	case san == "O-O-O":
-		if b.Side == board.WHITE {
-			return board.WCastleQueen, nil
-		} else {
-			return board.BCastleQueen, nil
+	case san == "O-O-O" || san == "O-O":
+		castle := board.WCastleKing
+		switch {
+		case san == "O-O-O" && b.Side == board.WHITE:
+			castle = board.WCastleQueen
+		case san == "O-O" && b.Side != board.WHITE:
+			castle = board.BCastleKing
+		case san == "O-O-O":
+			castle = board.BCastleQueen
 		}
-	case san == "O-O":
-		if b.Side == board.WHITE {
-			return board.WCastleKing, nil
-		} else {
-			return board.BCastleKing, nil
+		// Castling rights and an attacked path are only known to the move generator
+		for _, move := range b.MoveGenLegal() {
+			if move.String() == castle.String() {
+				return move, nil
+			}
 		}
+		return 0, fmt.Errorf("illegal castling: %s", san)
 	default:
-		sanRe := regexp.MustCompile(`^(?P<piece>[NBRQK])?(?P<disamb>[a-h]?[1-8]?)?(?P<capture>x?)(?P<target>[a-h][1-8])(?:=(?P<promo>[NBRQ]))?$`)
-		m := sanRe.FindStringSubmatch(san)
-		piece := m[sanRe.SubexpIndex("piece")]
-		disamb := m[sanRe.SubexpIndex("disamb")]
-		target := m[sanRe.SubexpIndex("target")]
-		promo := m[sanRe.SubexpIndex("promo")]
+		m := sanMatch.FindStringSubmatch(san)
+		if m == nil {
+			return 0, fmt.Errorf("invalid move: %s", san)
+		}
+		piece := m[sanMatch.SubexpIndex("piece")]
+		disamb := m[sanMatch.SubexpIndex("disamb")]
+		target := m[sanMatch.SubexpIndex("target")]
+		promo := m[sanMatch.SubexpIndex("promo")]
 		return getMoveWithFromTo(b, piece, target, disamb, promo)
 	}
 }
@@ -130,7 +133,7 @@ func getMoveWithFromTo(b *board.Board, pieceStr, to, dis, promo string) (board.M
 					}
 				}
 			}
-		} else if int(move.Piece()) == piece && move.To().String() == to {
+		} else if int(move.Piece()) == piece && move.To().String() == to && movePromo == promo {
 			return move, nil
 		}
 	}
@@ -0,0 +1,67 @@
+package pgn
+
+import (
+	"testing"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+func TestSANToMove(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		san  string
+		want string
+	}{
+		{"pawn push", "startpos", "e4", "e2e4"},
+		{"knight", "startpos", "Nf3", "g1f3"},
+		{"white short castling", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O", "e1g1"},
+		{"white long castling", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O-O", "e1c1"},
+		{"black short castling", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "O-O", "e8g8"},
+		{"black long castling", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "O-O-O", "e8c8"},
+		{"long castling next to an attacked square", "r3k2r/8/8/8/8/8/5r2/R3K2R w KQ - 0 1", "O-O-O", "e1c1"},
+		{"knight promotion", "8/4P3/8/8/8/8/k7/4K3 w - - 0 1", "e8=N", "e7e8n"},
+		{"queen promotion", "8/4P3/8/8/8/8/k7/4K3 w - - 0 1", "e8=Q", "e7e8q"},
+		{"capture promotion", "3r4/4P3/8/8/8/8/k7/4K3 w - - 0 1", "exd8=R", "e7d8r"},
+		{"black promotion", "4k3/8/8/8/8/8/p7/4K3 b - - 0 1", "a1=B", "a2a1b"},
+		{"file disambiguation", "4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "Rhd1", "h1d1"},
+		{"rank disambiguation", "4k3/8/8/8/R7/8/4K3/R7 w - - 0 1", "R1a2", "a1a2"},
+		{"square disambiguation", "4k3/8/8/8/8/2Q1Q3/4K3/4Q3 w - - 0 1", "Qe3d2", "e3d2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			move, err := SANToMove(board.NewBoard(tt.fen), tt.san)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if move.String() != tt.want {
+				t.Errorf("%s = %s, want %s", tt.san, move, tt.want)
+			}
+		})
+	}
+}
+
+func TestSANToMoveErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		san  string
+	}{
+		{"no castling rights", "r3k2r/8/8/8/8/8/8/R3K2R w - - 0 1", "O-O"},
+		{"castling through check", "r3k2r/8/8/8/8/8/5r2/R3K2R w KQ - 0 1", "O-O"},
+		{"castling out of check", "r3k2r/8/8/8/8/8/4r3/R3K2R w KQ - 0 1", "O-O-O"},
+		{"castling blocked", "startpos", "O-O"},
+		{"promotion without piece", "8/4P3/8/8/8/8/k7/4K3 w - - 0 1", "e8"},
+		{"illegal move", "startpos", "e5"},
+		{"invalid san", "startpos", "xyz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if move, err := SANToMove(board.NewBoard(tt.fen), tt.san); err == nil {
+				t.Errorf("%s = %s, want an error", tt.san, move)
+			}
+		})
+	}
+}
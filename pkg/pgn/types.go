@@ -9,7 +9,25 @@ const (
 	TERM_TIME   = "Time forfeit"
 )
 
-// Upper bounds for adjusted time per game (seconds), bullet assumes 40move game, blitz, rapid 60 move game.
+const START_FEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+const (
+	VARIANT_STANDARD = "Standard"
+	VARIANT_CHESS960 = "Chess960"
+)
+
+// Move assessment NAGs and their move mark equivalents.
+const (
+	NAG_NONE        = 0
+	NAG_GOOD        = 1 // !
+	NAG_MISTAKE     = 2 // ?
+	NAG_BRILLIANT   = 3 // !!
+	NAG_BLUNDER     = 4 // ??
+	NAG_INTERESTING = 5 // !?
+	NAG_DUBIOUS     = 6 // ?!
+)
+
+// Lower bounds for adjusted time per game (seconds) of the time control classes, see TimeControl.Seconds.
 const (
 	TC_BULLET    = 0
 	TC_BLITZ     = 3 * 60
@@ -17,6 +35,15 @@ const (
 	TC_CLASSICAL = 60 * 60
 )
 
+// Time control classes. Untimed games (`-`) are correspondence.
+const (
+	TC_CLASS_BULLET         = "bullet"
+	TC_CLASS_BLITZ          = "blitz"
+	TC_CLASS_RAPID          = "rapid"
+	TC_CLASS_CLASSICAL      = "classical"
+	TC_CLASS_CORRESPONDENCE = "correspondence"
+)
+
 type Tag string
 
 const (
@@ -32,30 +59,40 @@ const (
 	TAG_ECO         Tag = "ECO"
 	TAG_WHITE_ELO   Tag = "WhiteElo"
 	TAG_BLACK_ELO   Tag = "BlackElo"
+	TAG_VARIANT     Tag = "Variant"
+	TAG_FEN         Tag = "FEN"
+	TAG_SETUP       Tag = "SetUp"
+	TAG_UTCDATE     Tag = "UTCDate"
+	TAG_UTCTIME     Tag = "UTCTime"
 )
 
+type TagPair struct {
+	Tag   Tag
+	Value string
+}
+
 type PGNs []PGN
 
 type PGN struct {
-	Event string
-	// White       string
-	// Black       string
-	Result string
-	// WhiteElo    string
-	// BlackElo    string
-	// ECO         string
+	// Tag pairs in the order of the tag section, see the accessors in tags.go.
+	Tags  []TagPair
 	Moves string
 }
 
 type Parser struct {
 	clock     time.Time
 	source    Source
+	group     *ProgressGroup
 	pgn       *PGN
 	tempPGN   *PGN
 	tag       Tag
 	value     string
 	nextLine  string
 	gameCount int
+	filter    Filter
 	skipping  bool
-	filtered  bool
+	// State of the game being read
+	inGame     bool
+	inMovetext bool
+	inComment  bool
 }
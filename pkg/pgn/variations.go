@@ -0,0 +1,115 @@
+package pgn
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A line of play through the movetext from the starting position. Moves before Start are shared with the parent line.
+type Line struct {
+	SANs []string
+	// Move assessment NAG of each move from either a `$1` token or a move mark `!`.
+	NAGs []int
+	// Comments following each move, joined by a space when a move has several.
+	Comments  []string
+	Start     int
+	Variation bool
+}
+
+// Move assessment NAG of the i-th move, NAG_NONE if the move has none.
+func (l Line) NAG(i int) int {
+	if i < len(l.NAGs) {
+		return l.NAGs[i]
+	}
+
+	return NAG_NONE
+}
+
+// Comment following the i-th move, empty if the move has none.
+func (l Line) Comment(i int) string {
+	if i < len(l.Comments) {
+		return l.Comments[i]
+	}
+
+	return ""
+}
+
+func (l *Line) add(token Token) {
+	switch token.Type {
+	case TOKEN_SAN:
+		l.SANs = append(l.SANs, token.Value)
+		l.NAGs = append(l.NAGs, NAG_NONE)
+		l.Comments = append(l.Comments, "")
+	case TOKEN_NAG:
+		last := len(l.NAGs) - 1
+		if n, err := strconv.Atoi(token.Value); err == nil && n <= NAG_DUBIOUS && last >= 0 && l.NAGs[last] == NAG_NONE {
+			l.NAGs[last] = n
+		}
+	case TOKEN_COMMENT:
+		// Comments before the first move are about the game
+		last := len(l.Comments) - 1
+		if last >= 0 {
+			l.Comments[last] = strings.TrimSpace(l.Comments[last] + " " + token.Value)
+		}
+	}
+}
+
+// Get the main line skipping all variations.
+func (pgn *PGN) MainLine() Line {
+	line := Line{SANs: make([]string, 0), NAGs: make([]int, 0), Comments: make([]string, 0)}
+	depth := 0
+	for _, token := range pgn.Tokens() {
+		switch token.Type {
+		case TOKEN_VARIATION_START:
+			depth++
+		case TOKEN_VARIATION_END:
+			depth = max(depth-1, 0)
+		default:
+			if depth == 0 {
+				line.add(token)
+			}
+		}
+	}
+
+	return line
+}
+
+// Get the main line followed by every (nested) variation of the movetext. A variation replaces the last move of its parent line.
+func (pgn *PGN) GetLines() []Line {
+	lines := make([]Line, 0)
+	stack := []*Line{{SANs: make([]string, 0), NAGs: make([]int, 0), Comments: make([]string, 0)}}
+
+	for _, token := range pgn.Tokens() {
+		switch token.Type {
+		case TOKEN_VARIATION_START:
+			parent := stack[len(stack)-1]
+			start := max(len(parent.SANs)-1, 0)
+			variation := &Line{
+				SANs:      make([]string, start, start+8),
+				NAGs:      make([]int, start, start+8),
+				Comments:  make([]string, start, start+8),
+				Start:     start,
+				Variation: true,
+			}
+			copy(variation.SANs, parent.SANs[:start])
+			copy(variation.NAGs, parent.NAGs[:start])
+			copy(variation.Comments, parent.Comments[:start])
+			stack = append(stack, variation)
+		case TOKEN_VARIATION_END:
+			if len(stack) > 1 {
+				lines = append(lines, *stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			}
+		default:
+			stack[len(stack)-1].add(token)
+		}
+	}
+
+	// Unterminated variations
+	for len(stack) > 1 {
+		lines = append(lines, *stack[len(stack)-1])
+		stack = stack[:len(stack)-1]
+	}
+
+	return append([]Line{*stack[0]}, lines...)
+}
@@ -0,0 +1,70 @@
+package pgn
+
+import (
+	"github.com/inhies/go-bytesize"
+	"github.com/ulikunitz/xz"
+)
+
+type XzPGN struct {
+	pgn          *LineReader
+	inputReader  *ByteCountingReader
+	outputReader *ByteCountingReader
+	close        closeFn
+	path         string
+	size         bytesize.ByteSize
+}
+
+func NewXzPGN(path string) *XzPGN {
+	return &XzPGN{
+		path: path,
+	}
+}
+
+func (s *XzPGN) Open() error {
+	reader, size, close, err := openSource(s.path)
+	if err != nil {
+		return err
+	}
+	// Wrap the file and the xz Reader in ByteCountingReader to estimate the data size by output/input ratio
+	s.inputReader = &ByteCountingReader{reader: reader}
+	xzReader, err := xz.NewReader(s.inputReader)
+	if err != nil {
+		_ = close()
+		return err
+	}
+
+	s.outputReader = &ByteCountingReader{reader: xzReader}
+	s.close = close
+	s.size = size
+	s.pgn = NewLineReader(s.outputReader)
+
+	return nil
+}
+
+func (s *XzPGN) Close() error {
+	return s.close()
+}
+
+func (s *XzPGN) Scan() bool {
+	return s.pgn.Scan()
+}
+
+func (s *XzPGN) Text() string {
+	return s.pgn.Text()
+}
+
+func (s *XzPGN) Err() error {
+	return s.pgn.Err()
+}
+
+func (s *XzPGN) Size() bytesize.ByteSize {
+	if s.inputReader.bytesRead > 0 {
+		return s.size * (s.outputReader.bytesRead / s.inputReader.bytesRead)
+	}
+
+	return s.size
+}
+
+func (s *XzPGN) BytesRead() bytesize.ByteSize {
+	return s.outputReader.bytesRead
+}
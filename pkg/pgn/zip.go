@@ -0,0 +1,142 @@
+package pgn
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/inhies/go-bytesize"
+)
+
+// Reads every `.pgn` member of a zip archive (ie TWIC weekly archives) one after another.
+type ZipPGN struct {
+	pgn     *LineReader
+	reader  *ByteCountingReader
+	err     error
+	member  io.ReadCloser
+	close   closeFn
+	members []*zip.File
+	path    string
+	next    int
+	// Uncompressed data of the members read before the current one
+	bytesRead bytesize.ByteSize
+	size      bytesize.ByteSize
+}
+
+func NewZipPGN(path string) *ZipPGN {
+	return &ZipPGN{
+		path: path,
+	}
+}
+
+func (s *ZipPGN) Open() error {
+	reader, size, close, err := openSource(s.path)
+	if err != nil {
+		return err
+	}
+
+	// Zip requires random access, downloads are read into memory
+	readerAt, ok := reader.(io.ReaderAt)
+	if !ok {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			_ = close()
+			return err
+		}
+		readerAt = bytes.NewReader(data)
+		size = bytesize.ByteSize(len(data))
+	}
+
+	archive, err := zip.NewReader(readerAt, int64(size))
+	if err != nil {
+		_ = close()
+		return err
+	}
+
+	s.close = close
+	s.members = make([]*zip.File, 0)
+	for _, member := range archive.File {
+		if strings.EqualFold(filepath.Ext(member.Name), ".pgn") {
+			s.members = append(s.members, member)
+			s.size += bytesize.ByteSize(member.UncompressedSize64)
+		}
+	}
+
+	return nil
+}
+
+func (s *ZipPGN) openNext() bool {
+	if s.member != nil {
+		_ = s.member.Close()
+		s.bytesRead += s.reader.bytesRead
+		s.member = nil
+	}
+
+	for s.next < len(s.members) {
+		member, err := s.members[s.next].Open()
+		s.next++
+		if err != nil {
+			continue
+		}
+		s.member = member
+		s.reader = &ByteCountingReader{reader: member}
+		s.keepErr()
+		s.pgn = NewLineReader(s.reader)
+		return true
+	}
+
+	return false
+}
+
+// Keep the read error of the current member before it is replaced.
+func (s *ZipPGN) keepErr() {
+	if s.err == nil && s.pgn != nil {
+		s.err = s.pgn.Err()
+	}
+}
+
+func (s *ZipPGN) Close() error {
+	if s.member != nil {
+		_ = s.member.Close()
+	}
+
+	return s.close()
+}
+
+func (s *ZipPGN) Scan() bool {
+	for {
+		if s.pgn != nil && s.pgn.Scan() {
+			return true
+		}
+		if !s.openNext() {
+			return false
+		}
+	}
+}
+
+func (s *ZipPGN) Text() string {
+	return s.pgn.Text()
+}
+
+// The first read error of all members, nil before the first one is opened.
+func (s *ZipPGN) Err() error {
+	if s.err != nil || s.pgn == nil {
+		return s.err
+	}
+
+	return s.pgn.Err()
+}
+
+func (s *ZipPGN) Size() bytesize.ByteSize {
+	return s.size
+}
+
+func (s *ZipPGN) BytesRead() bytesize.ByteSize {
+	if s.member == nil {
+		return s.bytesRead
+	}
+
+	return s.bytesRead + s.reader.bytesRead
+}
@@ -0,0 +1,106 @@
+package pgn
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type zipMember struct {
+	name string
+	data string
+	// Store a wrong checksum so that reading the member fails
+	corrupt bool
+}
+
+func writeZip(t *testing.T, members ...zipMember) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "games.zip")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	w := zip.NewWriter(file)
+	for _, member := range members {
+		checksum := crc32.ChecksumIEEE([]byte(member.data))
+		if member.corrupt {
+			checksum++
+		}
+		fw, err := w.CreateRaw(&zip.FileHeader{
+			Name:               member.name,
+			Method:             zip.Store,
+			CRC32:              checksum,
+			CompressedSize64:   uint64(len(member.data)),
+			UncompressedSize64: uint64(len(member.data)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(member.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestZipMembers(t *testing.T) {
+	path := writeZip(t,
+		zipMember{name: "one.pgn", data: "1. e4 1-0\n"},
+		zipMember{name: "readme.txt", data: "not a game\n"},
+		zipMember{name: "sub/TWO.PGN", data: "1. d4 0-1\n"},
+	)
+	s := NewZipPGN(path)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	want := []string{"1. e4 1-0", "1. d4 0-1"}
+	if lines := readLines(t, s); !slices.Equal(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
+
+func TestZipWithoutPGN(t *testing.T) {
+	pp, err := NewPGNParser(NewZipPGN(writeZip(t, zipMember{name: "readme.txt", data: "not a game\n"})), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pp.Close()
+
+	if pp.Scan(context.Background()) {
+		t.Errorf("scanned a game from a zip without pgn members")
+	}
+	if err := pp.Err(); err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+}
+
+func TestZipKeepsFirstError(t *testing.T) {
+	path := writeZip(t,
+		zipMember{name: "one.pgn", data: "1. e4 1-0\n", corrupt: true},
+		zipMember{name: "two.pgn", data: "1. d4 0-1\n"},
+	)
+	s := NewZipPGN(path)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for s.Scan() {
+	}
+	if err := s.Err(); !errors.Is(err, zip.ErrChecksum) {
+		t.Errorf("err = %v, want %v", err, zip.ErrChecksum)
+	}
+}
@@ -1,16 +1,12 @@
 package pgn
 
 import (
-	"bufio"
-	"os"
-
 	"github.com/inhies/go-bytesize"
 	"github.com/klauspost/compress/zstd"
 )
 
 type ZstPGN struct {
-	file         *os.File
-	pgn          *bufio.Scanner
+	pgn          *LineReader
 	inputReader  *ByteCountingReader
 	outputReader *ByteCountingReader
 	close        closeFn
@@ -41,13 +37,13 @@ func (s *ZstPGN) Open() error {
 
 	s.close = close
 	s.size = size
-	s.pgn = bufio.NewScanner(bufio.NewReader(s.outputReader))
+	s.pgn = NewLineReader(s.outputReader)
 
 	return nil
 }
 
 func (s *ZstPGN) Close() error {
-	return s.file.Close()
+	return s.close()
 }
 
 func (s *ZstPGN) Scan() bool {
@@ -58,6 +54,10 @@ func (s *ZstPGN) Text() string {
 	return s.pgn.Text()
 }
 
+func (s *ZstPGN) Err() error {
+	return s.pgn.Err()
+}
+
 func (s *ZstPGN) Size() bytesize.ByteSize {
 	if s.inputReader.bytesRead > 0 {
 		return s.size * (s.outputReader.bytesRead / s.inputReader.bytesRead)
@@ -0,0 +1,97 @@
+package pgn
+
+import (
+	"io"
+
+	"github.com/inhies/go-bytesize"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Decodes the independent frames of a multi-frame zstd file (ie lichess database dumps) in parallel.
+type ParallelZstPGN struct {
+	pgn          *LineReader
+	inputReader  *ByteCountingReader
+	outputReader *ByteCountingReader
+	parallel     *parallelReader
+	decoder      *zstd.Decoder
+	// Sequential decoder of the remaining input once a frame is too large, nil until then
+	fallback *zstd.Decoder
+	close    closeFn
+	path     string
+	size     bytesize.ByteSize
+	workers  int
+}
+
+func NewParallelZstPGN(path string, workers int) *ParallelZstPGN {
+	return &ParallelZstPGN{
+		path:    path,
+		workers: max(workers, 1),
+	}
+}
+
+func (s *ParallelZstPGN) Open() error {
+	reader, size, close, err := openSource(s.path)
+	if err != nil {
+		return err
+	}
+	s.decoder, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(s.workers))
+	if err != nil {
+		_ = close()
+		return err
+	}
+
+	// Wrap the file and the decoded output in ByteCountingReader to estimate the data size by output/input ratio
+	s.inputReader = &ByteCountingReader{reader: reader}
+	decode := func(frame []byte) ([]byte, error) {
+		return s.decoder.DecodeAll(frame, nil)
+	}
+	fallback := func(r io.Reader) (io.Reader, error) {
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		s.fallback = decoder
+		return decoder, nil
+	}
+	s.parallel = newParallelReader(newZstdFrames(s.inputReader), decode, fallback, s.workers)
+	s.outputReader = &ByteCountingReader{reader: s.parallel}
+
+	s.close = close
+	s.size = size
+	s.pgn = NewLineReader(s.outputReader)
+
+	return nil
+}
+
+func (s *ParallelZstPGN) Close() error {
+	s.parallel.Close()
+	s.decoder.Close()
+	if s.fallback != nil {
+		s.fallback.Close()
+	}
+	return s.close()
+}
+
+func (s *ParallelZstPGN) Scan() bool {
+	return s.pgn.Scan()
+}
+
+func (s *ParallelZstPGN) Text() string {
+	return s.pgn.Text()
+}
+
+func (s *ParallelZstPGN) Err() error {
+	return s.pgn.Err()
+}
+
+func (s *ParallelZstPGN) Size() bytesize.ByteSize {
+	if s.inputReader.bytesRead > 0 {
+		return s.size * (s.outputReader.bytesRead / s.inputReader.bytesRead)
+	}
+
+	return s.size
+}
+
+func (s *ParallelZstPGN) BytesRead() bytesize.ByteSize {
+	return s.outputReader.bytesRead
+}
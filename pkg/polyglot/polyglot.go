@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -36,9 +35,45 @@ const (
 
 var MoveLimit int
 
+// Add every variation of the movetext to the book, not only the main line.
+var Variations bool
+
+// Weight of variation moves in percent of the weight of main line moves.
+var VariationWeight uint64 = 100
+
+// Weigh moves by the annotator's move marks and NAGs instead of the game result.
+var Repertoire bool
+
+// Only weigh the moves of one color in repertoire mode: white or black. Moves of both colors are weighed when empty.
+var RepertoireColor string
+
+// Accepts white, black or empty for both colors in any case.
+func NormalizeColor(name string) (string, error) {
+	switch color := strings.ToLower(name); color {
+	case "", "white", "black":
+		return color, nil
+	default:
+		return "", fmt.Errorf("unsupported color: %s, expected white or black", name)
+	}
+}
+
+// Weights of moves by their move assessment NAG in repertoire mode.
+var RepertoireWeights = map[int]uint64{
+	pgn.NAG_NONE:        10,
+	pgn.NAG_GOOD:        50,
+	pgn.NAG_MISTAKE:     0,
+	pgn.NAG_BRILLIANT:   100,
+	pgn.NAG_BLUNDER:     0,
+	pgn.NAG_INTERESTING: 20,
+	pgn.NAG_DUBIOUS:     2,
+}
+
 type Book struct {
 	book map[uint64][]polyEntry
-	lock sync.Mutex
+	// Names of the player the book is restricted to and the color they play. See NewPlayerBook.
+	players []string
+	color   int
+	lock    sync.Mutex
 }
 
 type polyEntry struct {
@@ -46,12 +81,28 @@ type polyEntry struct {
 	weight uint64
 }
 
+// A move stored in the book for a given position. Castling moves are in polyglot format ie e1h1.
+type BookMove struct {
+	Move   string
+	Weight uint64
+}
+
 func NewPolyglotBook() *Book {
 	return &Book{
 		book: make(map[uint64][]polyEntry),
 	}
 }
 
+// A book of the moves a player played with the color. The player is matched by any of their names or aliases against the White and Black tags.
+// Games where the player had the other color are ignored.
+func NewPlayerBook(color int, names ...string) *Book {
+	return &Book{
+		book:    make(map[uint64][]polyEntry),
+		players: names,
+		color:   color,
+	}
+}
+
 func decodeBookEntry(bytes []byte) (uint64, polyEntry) {
 	key := binary.BigEndian.Uint64(bytes[:8])
 	move := binary.BigEndian.Uint16(bytes[8:10])
@@ -104,34 +155,94 @@ func MoveToPolyMove(move board.Move) string {
 }
 
 func (pb *Book) AddFromPGN(game *pgn.PGN) {
-	b := board.NewBoard("startpos")
+	if pb.players != nil {
+		if color, ok := game.PlayerColor(pb.players...); !ok || color != pb.color {
+			return
+		}
+	}
 
-	movesSAN := game.RemoveAnnotations()
+	if Variations {
+		for _, line := range game.GetLines() {
+			pb.addLine(game, line)
+		}
+		return
+	}
 
-	re := regexp.MustCompile(`\d+\.\s|\s*1-0|\s*0-1|\s*1\/2-1\/2`)
-	movesSAN = re.ReplaceAllLiteralString(movesSAN, "")
-	SANs := strings.Fields(movesSAN)
+	pb.addLine(game, game.MainLine())
+}
+
+// Replay the line from the starting position of the game and add the moves from line.Start onwards.
+func (pb *Book) addLine(game *pgn.PGN, line pgn.Line) {
+	b := board.NewBoard(game.StartFEN())
+	var chess960 *pgn.Chess960
+	if game.IsVariant(pgn.VARIANT_CHESS960) {
+		var err error
+		chess960, b, err = pgn.NewChess960(game.Tag(pgn.TAG_FEN))
+		if err != nil {
+			log.Printf("fen: %s pgn: %+v\n", game.Tag(pgn.TAG_FEN), *game)
+			return
+		}
+	}
 
-	for i, san := range SANs {
+	for i, san := range line.SANs {
 		if i > MoveLimit-1 {
 			break
 		}
-		move, err := pgn.SANToMove(b, san)
-		if err != nil {
-			log.Printf("move: %s pgn: %+v\n", san, *game)
-			break
+		key, side := PolyZobrist(b), b.Side
+
+		var polyMove string
+		if chess960 != nil {
+			var err error
+			b, polyMove, err = chess960.Play(b, san)
+			if err != nil {
+				log.Printf("move: %s pgn: %+v\n", san, *game)
+				break
+			}
+		} else {
+			move, err := pgn.SANToMove(b, san)
+			if err != nil {
+				log.Printf("move: %s pgn: %+v\n", san, *game)
+				break
+			}
+			polyMove = MoveToPolyMove(move)
+			b.MakeMove(move)
+		}
+
+		if i < line.Start || (pb.players != nil && int(side) != pb.color) {
+			continue
 		}
 
+		var weight uint64
 		switch {
-		case (b.Side == board.WHITE && game.Result == "1-0") || (b.Side == board.BLACK && game.Result == "0-1"):
-			pb.AddMove(PolyZobrist(b), MoveToPolyMove(move), 2)
-		case game.Result == "1/2-1/2":
-			pb.AddMove(PolyZobrist(b), MoveToPolyMove(move), 1)
+		case Repertoire:
+			if RepertoireColor == "" || (RepertoireColor == "white") == (side == board.WHITE) {
+				weight = lineWeight(RepertoireWeights[line.NAG(i)], line.Variation)
+			}
+		case (side == board.WHITE && game.Result() == "1-0") || (side == board.BLACK && game.Result() == "0-1"):
+			weight = lineWeight(2, line.Variation)
+		case game.Result() == "1/2-1/2" || pb.players != nil:
+			// Player books keep everything the player played, losses included
+			weight = lineWeight(1, line.Variation)
+		}
+		if weight > 0 {
+			pb.AddMove(key, polyMove, weight)
 		}
-		b.MakeMove(move)
 	}
 }
 
+// When adding variations main line weights are scaled by 100 so that variation moves can be weighted by VariationWeight percent.
+func lineWeight(weight uint64, variation bool) uint64 {
+	switch {
+	case !Variations:
+		return weight
+	case variation:
+		return weight * VariationWeight
+	default:
+		return weight * 100
+	}
+}
+
+// Add the weight to the move of the position. Every occurrence adds its full weight, ie a move played by the winner of two games weighs 4.
 func (pb *Book) AddMove(key uint64, move string, weight uint64) {
 	pb.lock.Lock()
 	defer pb.lock.Unlock()
@@ -142,7 +253,7 @@ func (pb *Book) AddMove(key uint64, move string, weight uint64) {
 
 	for i := 0; i < len(moves); i++ {
 		if moves[i].move == move {
-			moves[i].weight++
+			moves[i].weight += weight
 			pb.book[key] = moves
 			return
 		}
@@ -151,6 +262,23 @@ func (pb *Book) AddMove(key uint64, move string, weight uint64) {
 	pb.book[key] = moves
 }
 
+// Probe the book for the moves stored for the position, ordered by descending weight.
+func (pb *Book) Probe(b *board.Board) []BookMove {
+	pb.lock.Lock()
+	defer pb.lock.Unlock()
+	entries := pb.book[PolyZobrist(b)]
+	moves := make([]BookMove, len(entries))
+	for i, entry := range entries {
+		moves[i] = BookMove{Move: entry.move, Weight: entry.weight}
+	}
+
+	sort.SliceStable(moves, func(i, j int) bool {
+		return moves[i].Weight > moves[j].Weight
+	})
+
+	return moves
+}
+
 func UCIToPolyMove(move string) uint16 {
 	var polyMove uint16
 	files := map[byte]uint16{
@@ -0,0 +1,87 @@
+package polyglot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/likeawizard/polyglot-composer/pkg/pgn"
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+func TestAddFromPGNWeights(t *testing.T) {
+	MoveLimit = 1
+	book := NewPolyglotBook()
+	for _, result := range []string{"1-0", "1-0", "1/2-1/2", "0-1"} {
+		game := &pgn.PGN{Moves: "1. e4 e5 " + result}
+		game.AddTag(pgn.TAG_RESULT, result)
+		book.AddFromPGN(game)
+	}
+
+	moves := book.Probe(board.NewBoard("startpos"))
+	if len(moves) != 1 || moves[0].Move != "e2e4" || moves[0].Weight != 5 {
+		t.Errorf("moves = %+v, want e2e4 with weight 5", moves)
+	}
+}
+
+func TestAddFromPGNInvalidFEN(t *testing.T) {
+	MoveLimit = 1
+	input := `[Result "1-0"]
+
+1. e4 e5 1-0
+
+[Result "1-0"]
+[SetUp "1"]
+[FEN "rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"]
+
+1. d4 d5 1-0
+
+[Result "1-0"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/8/4K2R w K - 0 1"]
+
+1. O-O Kd7 1-0
+`
+	pp, err := pgn.NewPGNParserFromReader(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pp.Close()
+
+	book := NewPolyglotBook()
+	games := 0
+	for pp.Scan(context.Background()) {
+		book.AddFromPGN(pp.PGN())
+		games++
+	}
+	if games != 2 {
+		t.Errorf("games = %d, want 2", games)
+	}
+	if moves := book.Probe(board.NewBoard("startpos")); len(moves) != 1 || moves[0].Move != "e2e4" {
+		t.Errorf("moves = %+v, want e2e4", moves)
+	}
+	if moves := book.Probe(board.NewBoard("4k3/8/8/8/8/8/8/4K2R w K - 0 1")); len(moves) != 1 || moves[0].Move != "e1h1" {
+		t.Errorf("moves = %+v, want e1h1", moves)
+	}
+}
+
+func TestNormalizeColor(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		err  bool
+	}{
+		{"", "", false},
+		{"white", "white", false},
+		{"Black", "black", false},
+		{"w", "", true},
+		{"whte", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeColor(tt.name)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("NormalizeColor(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}